@@ -0,0 +1,66 @@
+// Command modis-extract extracts values and view times at points from all pairs of datasets
+// found in HDF files under a root directory, e.g. for MOD11A1 daytime LST:
+//
+//	modis-extract -root /data/MOD11A1 -points stations.csv -n 3 -format csv \
+//	    -time 'Day_view_time$' -value 'LST_Day_1km$' > stations-lst.csv
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nordicsense/modis/extract"
+	"github.com/nordicsense/modis/ts"
+)
+
+func main() {
+	root := flag.String("root", ".", "root directory to scan for HDF files")
+	points := flag.String("points", "", "points file: CSV with id, lat and lon columns or GeoJSON")
+	timePattern := flag.String("time", "Day_view_time$", "pattern of the view time dataset")
+	valuePattern := flag.String("value", "LST_Day_1km$", "pattern of the value dataset")
+	n := flag.Int("n", 0, "size of the NxN neighbourhood to summarise, none if below 2")
+	format := flag.String("format", "csv", "output format: csv or jsonl")
+	output := flag.String("o", "", "output file, standard output if empty")
+	flag.Parse()
+
+	if err := run(*root, *points, *timePattern, *valuePattern, *n, *format, *output); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func run(root, pointsFile, timePattern, valuePattern string, n int, format, output string) error {
+	if pointsFile == "" {
+		return fmt.Errorf("points file required")
+	}
+	points, err := extract.ReadPoints(pointsFile)
+	if err != nil {
+		return err
+	}
+	pairs, err := ts.ListAll(root, ts.LayerPair{Time: timePattern, Value: valuePattern})
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if output != "" {
+		f, err := os.Create(output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	opts := &extract.Options{Neighbourhood: n}
+	var out extract.RowWriter
+	switch format {
+	case "csv":
+		out = extract.NewCSVWriter(w, opts)
+	case "jsonl":
+		out = extract.NewJSONLWriter(w)
+	default:
+		return fmt.Errorf("unknown format %s", format)
+	}
+	return extract.Extract(pairs, points, opts, out)
+}
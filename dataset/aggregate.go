@@ -0,0 +1,182 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// Aggregation defines how the values of a cell are combined when aggregating.
+type Aggregation int
+
+const (
+	AggregateMean Aggregation = iota
+	AggregateMedian
+	AggregateMin
+	AggregateMax
+	AggregateSum
+	// AggregateCount counts valid (non-NaN) values.
+	AggregateCount
+	// AggregateStd computes the population standard deviation.
+	AggregateStd
+	// AggregateMode takes the most frequent value, the smallest one on ties.
+	AggregateMode
+)
+
+// aggregateBlockLines defines the approximate number of source lines read at once.
+const aggregateBlockLines = 64
+
+// AggregateOptions define how cells of XFactor by YFactor source pixels are aggregated.
+// Cells with a fraction of valid values below MinValidFraction yield NaN; partial cells at
+// the right and bottom edges count pixels outside of the image as invalid.
+type AggregateOptions struct {
+	XFactor          int
+	YFactor          int
+	Method           Aggregation
+	MinValidFraction float64
+}
+
+// validate checks the factors, the method and the valid fraction.
+func (opts AggregateOptions) validate() error {
+	if opts.XFactor <= 0 || opts.YFactor <= 0 {
+		return fmt.Errorf("positive aggregation factors required, found %dx%d", opts.XFactor, opts.YFactor)
+	}
+	if opts.Method < AggregateMean || opts.Method > AggregateMode {
+		return fmt.Errorf("unknown aggregation method %d", opts.Method)
+	}
+	if opts.MinValidFraction < 0 || opts.MinValidFraction > 1 {
+		return fmt.Errorf("valid fraction must be within [0,1], found %v", opts.MinValidFraction)
+	}
+	return nil
+}
+
+// AggregateParams derives parameters of an image aggregated by given positive integer factors.
+// All other parameters, including the data type, are copied from p.
+func AggregateParams(p *modis.ImageParams, xFactor, yFactor int) (*modis.ImageParams, error) {
+	if xFactor <= 0 || yFactor <= 0 {
+		return nil, fmt.Errorf("positive aggregation factors required, found %dx%d", xFactor, yFactor)
+	}
+	at := p.Transform()
+	return p.ToBuilder().
+		Size((p.XSize()+xFactor-1)/xFactor, (p.YSize()+yFactor-1)/yFactor).
+		Transform(modis.AffineTransform{
+			at[0], at[1] * float64(xFactor), at[2] * float64(yFactor),
+			at[3], at[4] * float64(xFactor), at[5] * float64(yFactor),
+		}).
+		Build(), nil
+}
+
+// Aggregate aggregates src into dst, which must have the size of AggregateParams, streaming
+// through src by strips of cells.
+func Aggregate(src Reader, dst Writer, opts AggregateOptions) error {
+	if err := opts.validate(); err != nil {
+		return err
+	}
+	fx, fy := opts.XFactor, opts.YFactor
+	sp, dp := src.ImageParams(), dst.ImageParams()
+	expected, err := AggregateParams(sp, fx, fy)
+	if err != nil {
+		return err
+	}
+	if dp.XSize() != expected.XSize() || dp.YSize() != expected.YSize() {
+		return fmt.Errorf("expected target size %dx%d, found %dx%d", expected.XSize(), expected.YSize(), dp.XSize(), dp.YSize())
+	}
+	rows := num.MaxInt(aggregateBlockLines/fy, 1)
+	cell := make([]float64, 0, fx*fy)
+	for r0 := 0; r0 < dp.YSize(); r0 += rows {
+		n := num.MinInt(rows, dp.YSize()-r0)
+		sbox := modis.Box{0, r0 * fy, sp.XSize(), num.MinInt(n*fy, sp.YSize()-r0*fy)}
+		in, err := src.ReadBlock(0, 0, sbox)
+		if err != nil {
+			return err
+		}
+		out := make([]float64, n*dp.XSize())
+		for j := 0; j < n; j++ {
+			for i := 0; i < dp.XSize(); i++ {
+				cell = cell[:0]
+				for y := j * fy; y < num.MinInt((j+1)*fy, sbox[3]); y++ {
+					for x := i * fx; x < num.MinInt((i+1)*fx, sbox[2]); x++ {
+						if v := in[y*sbox[2]+x]; !math.IsNaN(v) {
+							cell = append(cell, v)
+						}
+					}
+				}
+				out[j*dp.XSize()+i] = aggregateCell(cell, fx*fy, opts)
+			}
+		}
+		if err = dst.WriteBlock(0, 0, modis.Box{0, r0, dp.XSize(), n}, out); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// AggregateToMemory aggregates src into a new in-memory dataset.
+func AggregateToMemory(src Reader, opts AggregateOptions) (*inMemory, error) {
+	if err := opts.validate(); err != nil {
+		return nil, err
+	}
+	p, err := AggregateParams(src.ImageParams(), opts.XFactor, opts.YFactor)
+	if err != nil {
+		return nil, err
+	}
+	res := NewInMemory(p)
+	if err := Aggregate(src, res, opts); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// aggregateCell combines the valid values of a cell of given size, values may be reordered.
+func aggregateCell(values []float64, size int, opts AggregateOptions) float64 {
+	if float64(len(values)) < opts.MinValidFraction*float64(size) {
+		return math.NaN()
+	}
+	if opts.Method == AggregateCount {
+		return float64(len(values))
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	switch opts.Method {
+	case AggregateMedian:
+		sort.Float64s(values)
+		n := len(values)
+		if n%2 == 1 {
+			return values[n/2]
+		}
+		return (values[n/2-1] + values[n/2]) / 2
+	case AggregateMin:
+		res := values[0]
+		for _, v := range values {
+			res = math.Min(res, v)
+		}
+		return res
+	case AggregateMax:
+		res := values[0]
+		for _, v := range values {
+			res = math.Max(res, v)
+		}
+		return res
+	case AggregateMode:
+		return modeOf(values)
+	}
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	switch opts.Method {
+	case AggregateSum:
+		return sum
+	case AggregateStd:
+		mean, ss := sum/float64(len(values)), 0.0
+		for _, v := range values {
+			ss += (v - mean) * (v - mean)
+		}
+		return math.Sqrt(ss / float64(len(values)))
+	}
+	return sum / float64(len(values))
+}
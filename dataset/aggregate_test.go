@@ -0,0 +1,89 @@
+package dataset_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestAggregate(t *testing.T) {
+	p := modis.ImageParamsBuilder(5, 4).Transform(modis.AffineTransform{100, 10, 0, 200, 0, -10}).Build()
+	src := dataset.NewInMemory(p)
+	values := []float64{
+		1, 2, 5, 5, 7,
+		3, 3, math.NaN(), 6, 8,
+		1, 1, 1, 1, math.NaN(),
+		1, 9, 2, 2, math.NaN(),
+	}
+	if err := src.WriteBlock(0, 0, modis.Box{0, 0, 5, 4}, values); err != nil {
+		t.Fatal(err)
+	}
+	ap, err := dataset.AggregateParams(p, 2, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ap.XSize() != 3 || ap.YSize() != 2 || ap.Transform() != (modis.AffineTransform{100, 20, 0, 200, 0, -20}) {
+		t.Fatalf("unexpected params %dx%d %v", ap.XSize(), ap.YSize(), ap.Transform())
+	}
+	nan := math.NaN()
+	cases := []struct {
+		method   dataset.Aggregation
+		fraction float64
+		expected []float64
+	}{
+		{dataset.AggregateMean, 0, []float64{2.25, 16. / 3, 7.5, 3, 1.5, nan}},
+		{dataset.AggregateMean, 0.5, []float64{2.25, 16. / 3, 7.5, 3, 1.5, nan}},
+		{dataset.AggregateMean, 0.75, []float64{2.25, 16. / 3, nan, 3, 1.5, nan}},
+		{dataset.AggregateMedian, 0, []float64{2.5, 5, 7.5, 1, 1.5, nan}},
+		{dataset.AggregateMin, 0, []float64{1, 5, 7, 1, 1, nan}},
+		{dataset.AggregateMax, 0, []float64{3, 6, 8, 9, 2, nan}},
+		{dataset.AggregateSum, 0, []float64{9, 16, 15, 12, 6, nan}},
+		{dataset.AggregateCount, 0, []float64{4, 3, 2, 4, 4, 0}},
+		{dataset.AggregateStd, 0, []float64{math.Sqrt(0.6875), math.Sqrt(2. / 9), 0.5, math.Sqrt(12), 0.5, nan}},
+		{dataset.AggregateMode, 0, []float64{3, 5, 7, 1, 1, nan}},
+	}
+	for _, c := range cases {
+		res, err := dataset.AggregateToMemory(src, dataset.AggregateOptions{XFactor: 2, YFactor: 2, Method: c.method, MinValidFraction: c.fraction})
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual, _ := res.ReadBlock(0, 0, modis.Box{0, 0, 3, 2})
+		for i, e := range c.expected {
+			if !(math.IsNaN(e) && math.IsNaN(actual[i])) && math.Abs(e-actual[i]) > 1e-12 {
+				t.Errorf("method %d, fraction %v: expected %v, found %v", c.method, c.fraction, c.expected, actual)
+				break
+			}
+		}
+	}
+	if err := dataset.Aggregate(src, dataset.NewInMemory(p), dataset.AggregateOptions{XFactor: 2, YFactor: 2}); err == nil {
+		t.Error("expected error for target size")
+	}
+	if _, err := dataset.AggregateToMemory(src, dataset.AggregateOptions{XFactor: 0, YFactor: 2}); err == nil {
+		t.Error("expected error for zero factor")
+	}
+	if _, err := dataset.AggregateParams(p, 2, 0); err == nil {
+		t.Error("expected error for zero factor of params")
+	}
+	if _, err := dataset.AggregateToMemory(src, dataset.AggregateOptions{XFactor: 2, YFactor: 2, Method: dataset.AggregateMode + 1}); err == nil {
+		t.Error("expected error for unknown method")
+	}
+}
+
+func TestAggregate_Strips(t *testing.T) {
+	p := modis.ImageParamsBuilder(12, 300).Build()
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	res, err := dataset.AggregateToMemory(src, dataset.AggregateOptions{XFactor: 3, YFactor: 5, Method: dataset.AggregateMin})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 60; y++ {
+		for x := 0; x < 4; x++ {
+			if v, _ := res.Read(x, y); v != float64(y*5*12+x*3) {
+				t.Fatalf("expected %d at (%d,%d), found %v", y*5*12+x*3, x, y, v)
+			}
+		}
+	}
+}
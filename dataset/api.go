@@ -13,8 +13,16 @@ type Reader interface {
 	ReadTime(x, y int) (time.Time, error)
 	ReadTimeAtLatLon(ll modis.LatLon) (time.Time, error)
 	ReadBlock(x, y int, box modis.Box) ([]float64, error)
+	// ReadRawBlock reads a block in the native data type without scale, offset or NaN substitution.
+	// Readers holding scaled values, such as in-memory datasets and views, convert them and fail
+	// with an *OverflowError if any is not representable, e.g. NaN without NoData.
+	ReadRawBlock(x, y int, box modis.Box) (*RawBuffer, error)
+	// BlockSize returns the natural block size of the underlying band.
+	BlockSize() (int, int)
+	// Blocks iterates over the image in natural block order, halo extends blocks by neighbouring pixels.
+	Blocks(halo int) *BlockIterator
 	ToMemory() *inMemory
-	Close()
+	Close() error
 }
 
 type Writer interface {
@@ -22,5 +30,28 @@ type Writer interface {
 	Write(x, y int, v float64) error
 	WriteAtLatLon(ll modis.LatLon, v float64) error
 	WriteBlock(x, y int, box modis.Box, buffer []float64) error
-	Close()
+	// WriteRawBlock writes a block of native values as they are, without scale, offset or NaN substitution.
+	WriteRawBlock(x, y int, box modis.Box, buffer *RawBuffer) error
+	// Close completes writing and reports errors of doing so, files may be incomplete otherwise.
+	Close() error
+}
+
+// Discard closes a writer discarding its output, e.g. after a failed computation, so that
+// atomic files are not moved into place and partial files are removed. Writers that cannot
+// discard their output are closed.
+func Discard(w Writer) error {
+	if d, ok := w.(interface{ Discard() error }); ok {
+		return d.Discard()
+	}
+	return w.Close()
+}
+
+// Overflows returns the number of values written to w that could not be represented in its data
+// type and were clamped (or, for NaN without NoData, written as 0, or, for valid values equal to
+// NoData once converted, read back as NaN). Writers that represent all values report 0.
+func Overflows(w Writer) int {
+	if o, ok := w.(interface{ Overflows() int }); ok {
+		return o.Overflows()
+	}
+	return 0
 }
@@ -0,0 +1,112 @@
+package dataset
+
+import (
+	"context"
+	"math"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// memoryBlockLines defines the number of image lines per block of in-memory datasets.
+const memoryBlockLines = 64
+
+// BlockIterator iterates over a Reader block by block in the natural block order of the
+// underlying band (left to right, top to bottom), optionally extending every block by a halo
+// of neighbouring pixels:
+//
+//	it := r.Blocks(1)
+//	for it.Next() {
+//		box, window, data := it.Box(), it.Window(), it.Data()
+//		...
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type BlockIterator struct {
+	ctx   context.Context
+	r     Reader
+	halo  int
+	boxes []modis.Box
+	next  int
+	box   modis.Box
+	data  []float64
+	err   error
+}
+
+func newBlockIterator(r Reader, halo int) *BlockIterator {
+	if halo < 0 {
+		halo = 0
+	}
+	bx, by := r.BlockSize()
+	return &BlockIterator{ctx: context.Background(), r: r, halo: halo, boxes: blockBoxes(r.ImageParams(), bx, by)}
+}
+
+// BlocksContext iterates over r like r.Blocks(halo) checking ctx before every block, once ctx
+// is cancelled Next returns false and Err returns ctx.Err().
+func BlocksContext(ctx context.Context, r Reader, halo int) *BlockIterator {
+	it := r.Blocks(halo)
+	it.ctx = ctx
+	return it
+}
+
+// Next reads the next block and reports whether there was one; it returns false when all
+// blocks were read or on error (see Err).
+func (it *BlockIterator) Next() bool {
+	if it.err != nil || it.next >= len(it.boxes) {
+		it.data = nil
+		return false
+	}
+	if it.err = it.ctx.Err(); it.err != nil {
+		it.data = nil
+		return false
+	}
+	it.box = it.boxes[it.next]
+	it.next++
+	it.data, it.err = readWindow(it.r, it.Window())
+	return it.err == nil
+}
+
+// Box returns the current block.
+func (it *BlockIterator) Box() modis.Box {
+	return it.box
+}
+
+// Window returns the current block extended by the halo, it may extend beyond the image.
+func (it *BlockIterator) Window() modis.Box {
+	return modis.Box{it.box[0] - it.halo, it.box[1] - it.halo, it.box[2] + 2*it.halo, it.box[3] + 2*it.halo}
+}
+
+// Data returns the values of the current window row by row, pixels outside the image are NaN.
+func (it *BlockIterator) Data() []float64 {
+	return it.data
+}
+
+func (it *BlockIterator) Err() error {
+	return it.err
+}
+
+// readWindow reads a window that may extend beyond the image filling the outside with NaN.
+func readWindow(r Reader, window modis.Box) ([]float64, error) {
+	p := r.ImageParams()
+	x0, y0 := num.MaxInt(window[0], 0), num.MaxInt(window[1], 0)
+	x1, y1 := num.MinInt(window[0]+window[2], p.XSize()), num.MinInt(window[1]+window[3], p.YSize())
+	if x0 == window[0] && y0 == window[1] && x1 == window[0]+window[2] && y1 == window[1]+window[3] {
+		return r.ReadBlock(0, 0, window)
+	}
+	res := make([]float64, window[2]*window[3])
+	for i := range res {
+		res[i] = math.NaN()
+	}
+	if x1 <= x0 || y1 <= y0 {
+		return res, nil
+	}
+	inner, err := r.ReadBlock(0, 0, modis.Box{x0, y0, x1 - x0, y1 - y0})
+	if err != nil {
+		return nil, err
+	}
+	for j := y0; j < y1; j++ {
+		copy(res[(j-window[1])*window[2]+x0-window[0]:], inner[(j-y0)*(x1-x0):(j-y0+1)*(x1-x0)])
+	}
+	return res, nil
+}
@@ -0,0 +1,88 @@
+package dataset_test
+
+import (
+	"math"
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func fillSequence(t *testing.T, w dataset.Writer) {
+	p := w.ImageParams()
+	buf := make([]float64, p.XSize()*p.YSize())
+	for i := range buf {
+		buf[i] = float64(i)
+	}
+	if err := w.WriteBlock(0, 0, modis.Box{0, 0, p.XSize(), p.YSize()}, buf); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func assertBlocks(t *testing.T, r dataset.Reader, halo int) {
+	p := r.ImageParams()
+	bx, by := r.BlockSize()
+	covered := make([]int, p.XSize()*p.YSize())
+	it := r.Blocks(halo)
+	for it.Next() {
+		box, window, data := it.Box(), it.Window(), it.Data()
+		if box[0]%bx != 0 || box[1]%by != 0 {
+			t.Errorf("block %v not aligned to %dx%d", box, bx, by)
+		}
+		if len(data) != window[2]*window[3] {
+			t.Fatalf("expected %d values, found %d", window[2]*window[3], len(data))
+		}
+		for j := 0; j < window[3]; j++ {
+			for i := 0; i < window[2]; i++ {
+				x, y := window[0]+i, window[1]+j
+				v := data[j*window[2]+i]
+				if x < 0 || y < 0 || x >= p.XSize() || y >= p.YSize() {
+					if !math.IsNaN(v) {
+						t.Errorf("expected NaN outside image at (%d,%d), found %v", x, y, v)
+					}
+				} else if v != float64(y*p.XSize()+x) {
+					t.Errorf("expected %d at (%d,%d), found %v", y*p.XSize()+x, x, y, v)
+				}
+			}
+		}
+		for j := box[1]; j < box[1]+box[3]; j++ {
+			for i := box[0]; i < box[0]+box[2]; i++ {
+				covered[j*p.XSize()+i]++
+			}
+		}
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i, n := range covered {
+		if n != 1 {
+			t.Fatalf("pixel %d covered %d times", i, n)
+		}
+	}
+}
+
+func TestBlocks_InMemory(t *testing.T) {
+	ds := dataset.NewInMemory(modis.ImageParamsBuilder(7, 150).Build())
+	fillSequence(t, ds)
+	assertBlocks(t, ds, 0)
+	assertBlocks(t, ds, 2)
+}
+
+func TestBlocks_File(t *testing.T) {
+	p := modis.ImageParamsBuilder(40, 35).DataType(gdal.Int32).Build()
+	opts := &dataset.CreateOptions{Tiled: true, BlockXSize: 16, BlockYSize: 16}
+	w, err := dataset.New(path.Join(t.TempDir(), "tiled.tif"), dataset.GTiff, p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	fillSequence(t, w)
+	r := w.(dataset.Reader)
+	if bx, by := r.BlockSize(); bx != 16 || by != 16 {
+		t.Errorf("expected 16x16 blocks, found %dx%d", bx, by)
+	}
+	assertBlocks(t, r, 0)
+	assertBlocks(t, r, 3)
+}
@@ -0,0 +1,139 @@
+package dataset
+
+import (
+	"container/list"
+	"fmt"
+	"sync"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// CacheStats reports the usage of the block cache of a reader opened with OpenCached.
+type CacheStats struct {
+	Hits   int64
+	Misses int64
+	// Bytes currently used by cached blocks out of the Budget.
+	Bytes  int
+	Budget int
+}
+
+// Cached is implemented by readers with a block cache.
+type Cached interface {
+	CacheStats() CacheStats
+}
+
+// OpenCached opens a file like Open, but keeps up to budget bytes of decoded blocks in an LRU
+// cache, so that repeated reads of nearby pixels (Read, ReadAtLatLon, ReadTime etc.) are served
+// from memory. The returned reader implements Cached.
+func OpenCached(fileName string, budget int) (Reader, error) {
+	r, err := Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	ds := r.(*imageFile)
+	ds.cache = newBlockCache(budget)
+	return ds, nil
+}
+
+func (ds *imageFile) CacheStats() CacheStats {
+	if ds.cache == nil {
+		return CacheStats{}
+	}
+	return ds.cache.stats()
+}
+
+// readCached assembles the box from (cached) natural blocks of the band.
+func (ds *imageFile) readCached(x, y int, box modis.Box) ([]float64, error) {
+	nx, ny := ds.ImageParams().XSize(), ds.ImageParams().YSize()
+	x0, y0 := x+box[0], y+box[1]
+	if x0 < 0 || y0 < 0 || box[2] < 0 || box[3] < 0 || x0+box[2] > nx || y0+box[3] > ny {
+		return nil, fmt.Errorf("box %v at {x:%d, y:%d} is outside of image area {x:[0,%d), y:[0,%d)}", box, x, y, nx, ny)
+	}
+	bx, by := ds.BlockSize()
+	if bx <= 0 || by <= 0 {
+		return ds.readBlock(x, y, box)
+	}
+	res := make([]float64, box[2]*box[3])
+	for j := y0 / by; j*by < y0+box[3]; j++ {
+		for i := x0 / bx; i*bx < x0+box[2]; i++ {
+			block := modis.Box{i * bx, j * by, num.MinInt(bx, nx-i*bx), num.MinInt(by, ny-j*by)}
+			data, ok := ds.cache.get(i, j)
+			if !ok {
+				var err error
+				if data, err = ds.readBlock(0, 0, block); err != nil {
+					return nil, err
+				}
+				ds.cache.put(i, j, data)
+			}
+			// copy the intersection of the block and the box
+			cx0, cx1 := num.MaxInt(x0, block[0]), num.MinInt(x0+box[2], block[0]+block[2])
+			for yy := num.MaxInt(y0, block[1]); yy < num.MinInt(y0+box[3], block[1]+block[3]); yy++ {
+				from := (yy-block[1])*block[2] + cx0 - block[0]
+				copy(res[(yy-y0)*box[2]+cx0-x0:], data[from:from+cx1-cx0])
+			}
+		}
+	}
+	return res, nil
+}
+
+type blockCache struct {
+	mu      sync.Mutex
+	budget  int
+	bytes   int
+	lru     *list.List
+	entries map[[2]int]*list.Element
+	hits    int64
+	misses  int64
+}
+
+type cacheEntry struct {
+	key  [2]int
+	data []float64
+}
+
+const float64Bytes = 8
+
+func newBlockCache(budget int) *blockCache {
+	return &blockCache{budget: budget, lru: list.New(), entries: make(map[[2]int]*list.Element)}
+}
+
+func (c *blockCache) get(i, j int) ([]float64, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[[2]int{i, j}]; ok {
+		c.hits++
+		c.lru.MoveToFront(e)
+		return e.Value.(*cacheEntry).data, true
+	}
+	c.misses++
+	return nil, false
+}
+
+func (c *blockCache) put(i, j int, data []float64) {
+	size := len(data) * float64Bytes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if size > c.budget {
+		return
+	}
+	key := [2]int{i, j}
+	if e, ok := c.entries[key]; ok {
+		c.bytes -= len(e.Value.(*cacheEntry).data) * float64Bytes
+		c.lru.Remove(e)
+	}
+	for c.bytes+size > c.budget {
+		last := c.lru.Back()
+		entry := c.lru.Remove(last).(*cacheEntry)
+		delete(c.entries, entry.key)
+		c.bytes -= len(entry.data) * float64Bytes
+	}
+	c.entries[key] = c.lru.PushFront(&cacheEntry{key: key, data: data})
+	c.bytes += size
+}
+
+func (c *blockCache) stats() CacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return CacheStats{Hits: c.hits, Misses: c.misses, Bytes: c.bytes, Budget: c.budget}
+}
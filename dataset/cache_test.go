@@ -0,0 +1,73 @@
+package dataset_test
+
+import (
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestOpenCached(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "cached.tif")
+	p := modis.ImageParamsBuilder(64, 48).DataType(gdal.Int32).Build()
+	w, err := dataset.New(fileName, dataset.GTiff, p, &dataset.CreateOptions{Tiled: true, BlockXSize: 16, BlockYSize: 16})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillSequence(t, w)
+	w.Close()
+
+	const blockBytes = 16 * 16 * 8
+	r, err := dataset.OpenCached(fileName, 2*blockBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for j := 0; j < 16; j++ {
+		for i := 0; i < 20; i++ { // spans two blocks
+			v, err := r.Read(i, j)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if v != float64(j*64+i) {
+				t.Errorf("expected %d at (%d,%d), found %v", j*64+i, i, j, v)
+			}
+		}
+	}
+	stats := r.(dataset.Cached).CacheStats()
+	if stats.Misses != 2 || stats.Hits != 16*20-2 {
+		t.Errorf("expected 2 misses and %d hits, found %+v", 16*20-2, stats)
+	}
+	if stats.Bytes != 2*blockBytes || stats.Budget != 2*blockBytes {
+		t.Errorf("expected cache to be full, found %+v", stats)
+	}
+
+	// reading a third block evicts the least recently used one
+	if _, err = r.Read(40, 40); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Read(0, 0); err != nil {
+		t.Fatal(err)
+	}
+	if stats = r.(dataset.Cached).CacheStats(); stats.Misses != 4 || stats.Bytes > stats.Budget {
+		t.Errorf("expected eviction within budget, found %+v", stats)
+	}
+
+	box := modis.Box{5, 7, 40, 30}
+	buf, err := r.ReadBlock(0, 0, box)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for j := 0; j < box[3]; j++ {
+		for i := 0; i < box[2]; i++ {
+			if expected := float64((box[1]+j)*64 + box[0] + i); buf[j*box[2]+i] != expected {
+				t.Fatalf("expected %v at (%d,%d), found %v", expected, i, j, buf[j*box[2]+i])
+			}
+		}
+	}
+	if _, err = r.Read(64, 0); err == nil {
+		t.Error("expected error outside of image")
+	}
+}
@@ -0,0 +1,178 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// clipEdgeSamples defines the number of points per edge used to find the pixel extent of a lat/lon box.
+const clipEdgeSamples = 20
+
+// Clip returns a lazy view of the box of r as a Reader with the transform shifted accordingly.
+func Clip(r Reader, box modis.Box) (Reader, error) {
+	p := r.ImageParams()
+	if box[0] < 0 || box[1] < 0 || box[2] <= 0 || box[3] <= 0 || box[0]+box[2] > p.XSize() || box[1]+box[3] > p.YSize() {
+		return nil, fmt.Errorf("box %v is outside of image area {x:[0,%d), y:[0,%d)}", box, p.XSize(), p.YSize())
+	}
+	at := p.Transform()
+	bx, by := r.BlockSize()
+	return &derived{
+		p: p.ToBuilder().
+			Size(box[2], box[3]).
+			Transform(modis.AffineTransform{
+				at[0] + float64(box[0])*at[1] + float64(box[1])*at[2], at[1], at[2],
+				at[3] + float64(box[0])*at[4] + float64(box[1])*at[5], at[4], at[5],
+			}).
+			Build(),
+		bx: bx,
+		by: by,
+		block: func(b modis.Box) ([]float64, error) {
+			return r.ReadBlock(box[0], box[1], b)
+		},
+	}, nil
+}
+
+// ClipLatLon returns a lazy view of the part of r covering the lat/lon box given by its
+// south-west and north-east corners in degrees.
+func ClipLatLon(r Reader, sw, ne modis.LatLon) (Reader, error) {
+	var lls []modis.LatLon
+	for i := 0; i <= clipEdgeSamples; i++ {
+		f := float64(i) / clipEdgeSamples
+		lat, lon := sw[0]+f*(ne[0]-sw[0]), sw[1]+f*(ne[1]-sw[1])
+		lls = append(lls, modis.LatLon{sw[0], lon}, modis.LatLon{ne[0], lon}, modis.LatLon{lat, sw[1]}, modis.LatLon{lat, ne[1]})
+	}
+	box, err := pixelBounds(r.ImageParams(), lls)
+	if err != nil {
+		return nil, err
+	}
+	return Clip(r, box)
+}
+
+// ClipPolygon returns a lazy view of the part of r covering the bounding box of the polygon
+// with pixels whose centres are outside of the polygon set to NaN.
+func ClipPolygon(r Reader, pg modis.Polygon) (Reader, error) {
+	if len(pg) == 0 {
+		return nil, fmt.Errorf("empty polygon")
+	}
+	p := r.ImageParams()
+	pixels := make(pixelPolygon, len(pg))
+	var box modis.Box
+	for i, ring := range pg {
+		xs, ys, err := latLonPixels(p, ring)
+		if err != nil {
+			return nil, err
+		}
+		if i == 0 {
+			if box, err = pixelBox(p, xs, ys); err != nil {
+				return nil, err
+			}
+		}
+		for j := range xs {
+			pixels[i] = append(pixels[i], pixelPoint{xs[j], ys[j]})
+		}
+	}
+	view, err := Clip(r, box)
+	if err != nil {
+		return nil, err
+	}
+	clip := view.(*derived)
+	block := clip.block
+	clip.block = func(b modis.Box) ([]float64, error) {
+		res, err := block(b)
+		if err != nil {
+			return nil, err
+		}
+		for j := 0; j < b[3]; j++ {
+			for i := 0; i < b[2]; i++ {
+				if !pixels.contains(float64(box[0]+b[0]+i)+0.5, float64(box[1]+b[1]+j)+0.5) {
+					res[j*b[2]+i] = math.NaN()
+				}
+			}
+		}
+		return res, nil
+	}
+	return clip, nil
+}
+
+// pixelPoint is a point in fractional pixel coordinates of an image.
+type pixelPoint struct{ x, y float64 }
+
+// pixelPolygon is a polygon in fractional pixel coordinates of an image, exterior ring first.
+type pixelPolygon [][]pixelPoint
+
+// contains tests whether the point lies within the exterior ring and outside of all holes.
+func (pg pixelPolygon) contains(x, y float64) bool {
+	inside := false
+	for _, ring := range pg {
+		// even-odd rule: every ring crossed toggles the state
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a.y > y) != (b.y > y) && x < (b.x-a.x)*(y-a.y)/(b.y-a.y)+a.x {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// pixelBounds returns the box of the image covering all points.
+func pixelBounds(p *modis.ImageParams, lls []modis.LatLon) (modis.Box, error) {
+	xs, ys, err := latLonPixels(p, lls)
+	if err != nil {
+		return modis.Box{}, err
+	}
+	return pixelBox(p, xs, ys)
+}
+
+// pixelBox returns the box of the image covering all fractional pixel coordinates.
+func pixelBox(p *modis.ImageParams, xs, ys []float64) (modis.Box, error) {
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i := range xs {
+		if num.IsFinite(xs[i]) && num.IsFinite(ys[i]) {
+			minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+			minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+		}
+	}
+	x0, y0 := num.MaxInt(int(math.Floor(minX)), 0), num.MaxInt(int(math.Floor(minY)), 0)
+	x1, y1 := num.MinInt(int(math.Ceil(maxX)), p.XSize()), num.MinInt(int(math.Ceil(maxY)), p.YSize())
+	if x1 <= x0 || y1 <= y0 {
+		return modis.Box{}, fmt.Errorf("area does not intersect the image")
+	}
+	return modis.Box{x0, y0, x1 - x0, y1 - y0}, nil
+}
+
+// latLonPixels converts lat/lon points in degrees into fractional pixel coordinates of the image.
+func latLonPixels(p *modis.ImageParams, lls []modis.LatLon) ([]float64, []float64, error) {
+	inv, err := p.Transform().Invert()
+	if err != nil {
+		return nil, nil, err
+	}
+	wkt, err := wgs84WKT()
+	if err != nil {
+		return nil, nil, err
+	}
+	xs, ys := make([]float64, len(lls)), make([]float64, len(lls))
+	for i, ll := range lls {
+		xs[i], ys[i] = ll[1], ll[0]
+	}
+	ct := newPixelTransform(wkt, p.Projection(), modis.AffineTransform{0, 1, 0, 0, 0, 1})
+	defer ct.destroy()
+	xs, ys = ct.forward(xs, ys)
+	for i := range xs {
+		xs[i], ys[i] = applyTransform(inv, xs[i], ys[i])
+	}
+	return xs, ys, nil
+}
+
+func wgs84WKT() (string, error) {
+	wgs84 := gdal.CreateSpatialReference("")
+	defer wgs84.Destroy()
+	if err := wgs84.FromEPSG(4326); err != nil {
+		return "", err
+	}
+	return wgs84.ToWKT()
+}
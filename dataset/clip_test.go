@@ -0,0 +1,110 @@
+package dataset_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestClip(t *testing.T) {
+	p := modis.ImageParamsBuilder(20, 30).Transform(modis.AffineTransform{1000, 10, 0, 5000, 0, -10}).Build()
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	view, err := dataset.Clip(src, modis.Box{5, 10, 4, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	vp := view.ImageParams()
+	if vp.XSize() != 4 || vp.YSize() != 6 || vp.Transform() != (modis.AffineTransform{1050, 10, 0, 4900, 0, -10}) {
+		t.Errorf("unexpected params %dx%d %v", vp.XSize(), vp.YSize(), vp.Transform())
+	}
+	values, err := view.ReadBlock(1, 2, modis.Box{0, 0, 2, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []float64{12*20 + 6, 12*20 + 7, 13*20 + 6, 13*20 + 7} {
+		if values[i] != expected {
+			t.Errorf("expected %v, found %v", expected, values[i])
+		}
+	}
+	// the view is lazy
+	_ = src.Write(5, 10, -1)
+	if v, _ := view.Read(0, 0); v != -1 {
+		t.Errorf("expected -1, found %v", v)
+	}
+	if _, err = view.Read(4, 0); err == nil {
+		t.Error("expected error outside of view")
+	}
+	if _, err = dataset.Clip(src, modis.Box{18, 0, 4, 4}); err == nil {
+		t.Error("expected error for box outside of image")
+	}
+	n := 0
+	for it := view.Blocks(0); it.Next(); {
+		n += len(it.Data())
+	}
+	if n != 4*6 {
+		t.Errorf("expected blocks to cover 24 pixels, found %d", n)
+	}
+}
+
+func TestClipLatLon(t *testing.T) {
+	p := testParams()
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	at := p.Transform()
+	// corners of pixels (2,1) to (5,4) inclusive, shrunk to stay inside
+	nw := at.Pixels2LatLon(2, 1)
+	se := at.Pixels2LatLon(6, 5)
+	ne := modis.LatLon{nw[0] - 1e-6, se[1] - 1e-6}
+	sw := modis.LatLon{se[0] + 1e-6, nw[1] + 1e-6}
+	view, err := dataset.ClipLatLon(src, sw, ne)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vp := view.ImageParams()
+	if vp.XSize() < 4 || vp.YSize() < 4 {
+		t.Fatalf("expected view to cover at least 4x4 pixels, found %dx%d", vp.XSize(), vp.YSize())
+	}
+	if _, err = dataset.ClipLatLon(src, modis.LatLon{-10, -10}, modis.LatLon{-5, -5}); err == nil {
+		t.Error("expected error for area outside of image")
+	}
+}
+
+func TestClipPolygon(t *testing.T) {
+	p := testParams()
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	at := p.Transform()
+	pg := modis.Polygon{{at.Pixels2LatLon(0, 0), at.Pixels2LatLon(8, 0), at.Pixels2LatLon(0, 6)}}
+	view, err := dataset.ClipPolygon(src, pg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	vp := view.ImageParams()
+	if vp.XSize() != 8 || vp.YSize() != 6 {
+		t.Fatalf("unexpected size %dx%d", vp.XSize(), vp.YSize())
+	}
+	values, err := view.ReadBlock(0, 0, modis.Box{0, 0, 8, 6})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 6; y++ {
+		for x := 0; x < 8; x++ {
+			v := values[y*8+x]
+			if (float64(x)+0.5)/8+(float64(y)+0.5)/6 < 1 {
+				if v != float64(y*8+x) {
+					t.Errorf("expected %d at (%d,%d), found %v", y*8+x, x, y, v)
+				}
+			} else if !math.IsNaN(v) {
+				t.Errorf("expected NaN at (%d,%d), found %v", x, y, v)
+			}
+		}
+	}
+	if m := view.ToMemory(); m == nil {
+		t.Error("expected view in memory")
+	} else if v, _ := m.Read(7, 5); !math.IsNaN(v) {
+		t.Errorf("expected NaN, found %v", v)
+	}
+}
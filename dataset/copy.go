@@ -0,0 +1,39 @@
+package dataset
+
+import (
+	"context"
+
+	"github.com/nordicsense/modis"
+)
+
+// Copy writes all values of src to the aligned dst block by block, checking ctx between blocks
+// and returning ctx.Err() once it is cancelled. dst is not closed.
+func Copy(ctx context.Context, dst Writer, src Reader) error {
+	return Apply(ctx, dst, func(box modis.Box, in [][]float64, out []float64) error {
+		copy(out, in[0])
+		return nil
+	}, &ApplyOptions{Workers: 1}, src)
+}
+
+// ToMemoryContext reads r into a new in-memory dataset, checking ctx between blocks.
+func ToMemoryContext(ctx context.Context, r Reader) (Reader, error) {
+	res := NewInMemory(r.ImageParams().ToBuilder().Build())
+	if err := Copy(ctx, res, r); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ToFile writes r to a new file, checking ctx between blocks. On failure or cancellation the
+// output is discarded.
+func ToFile(ctx context.Context, r Reader, fileName string, driver Driver, opts *CreateOptions) error {
+	w, err := New(fileName, driver, r.ImageParams(), opts)
+	if err != nil {
+		return err
+	}
+	if err = Copy(ctx, w, r); err != nil {
+		_ = Discard(w)
+		return err
+	}
+	return w.Close()
+}
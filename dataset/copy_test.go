@@ -0,0 +1,57 @@
+package dataset_test
+
+import (
+	"context"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestToFile(t *testing.T) {
+	src := dataset.NewInMemory(modis.ImageParamsBuilder(7, 150).DataType(gdal.Int32).Build())
+	fillSequence(t, src)
+	fileName := path.Join(t.TempDir(), "copy.tif")
+	if err := dataset.ToFile(context.Background(), src, fileName, dataset.GTiff, nil); err != nil {
+		t.Fatal(err)
+	}
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	assertBlocks(t, r, 1)
+	m, err := dataset.ToMemoryContext(context.Background(), r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertBlocks(t, m, 0)
+}
+
+func TestContext_Cancelled(t *testing.T) {
+	src := dataset.NewInMemory(modis.ImageParamsBuilder(7, 150).Build())
+	fillSequence(t, src)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	it := dataset.BlocksContext(ctx, src, 0)
+	if it.Next() {
+		t.Error("expected no blocks")
+	}
+	if it.Err() != context.Canceled {
+		t.Errorf("expected %v, found %v", context.Canceled, it.Err())
+	}
+	if _, err := dataset.ToMemoryContext(ctx, src); err != context.Canceled {
+		t.Errorf("expected %v, found %v", context.Canceled, err)
+	}
+	fileName := path.Join(t.TempDir(), "cancelled.tif")
+	if err := dataset.ToFile(ctx, src, fileName, dataset.GTiff, nil); err != context.Canceled {
+		t.Errorf("expected %v, found %v", context.Canceled, err)
+	}
+	if _, err := os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("expected cancelled output to be removed, found %v", err)
+	}
+}
@@ -0,0 +1,102 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/nordicsense/modis"
+)
+
+// derived implements a read-only Reader view given a function reading blocks within the image
+// area of the view. Views read lazily from their parents and Close does not close the parent.
+type derived struct {
+	p      *modis.ImageParams
+	bx, by int
+	block  func(box modis.Box) ([]float64, error)
+}
+
+// NewView returns a read-only Reader over the grid of p with natural blocks of bx by by pixels,
+// computing values lazily with block, which receives boxes in absolute pixel coordinates.
+func NewView(p *modis.ImageParams, bx, by int, block func(box modis.Box) ([]float64, error)) Reader {
+	return &derived{p: p, bx: bx, by: by, block: block}
+}
+
+func (ds *derived) ImageParams() *modis.ImageParams {
+	return ds.p
+}
+
+func (ds *derived) Read(x, y int) (float64, error) {
+	res, err := ds.ReadBlock(x, y, modis.Box{0, 0, 1, 1})
+	if err != nil {
+		return math.NaN(), err
+	}
+	return res[0], nil
+}
+
+func (ds *derived) ReadAtLatLon(ll modis.LatLon) (float64, error) {
+	x, y := ds.ImageParams().Transform().LatLon2Pixels(ll)
+	return ds.Read(x, y)
+}
+
+func (ds *derived) ReadTime(x, y int) (time.Time, error) {
+	v, err := ds.Read(x, y)
+	if err != nil {
+		return time.Time{}, err
+	}
+	ll := ds.ImageParams().Transform().Pixels2LatLon(x, y)
+	return ds.ImageParams().Value2time(v, ll)
+}
+
+func (ds *derived) ReadTimeAtLatLon(ll modis.LatLon) (time.Time, error) {
+	v, err := ds.ReadAtLatLon(ll)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ds.ImageParams().Value2time(v, ll)
+}
+
+func (ds *derived) ReadBlock(x, y int, box modis.Box) ([]float64, error) {
+	nx, ny := ds.ImageParams().XSize(), ds.ImageParams().YSize()
+	x0, y0 := x+box[0], y+box[1]
+	if x0 < 0 || y0 < 0 || box[2] < 0 || box[3] < 0 || x0+box[2] > nx || y0+box[3] > ny {
+		return nil, fmt.Errorf("box %v at {x:%d, y:%d} is outside of image area {x:[0,%d), y:[0,%d)}", box, x, y, nx, ny)
+	}
+	return ds.block(modis.Box{x0, y0, box[2], box[3]})
+}
+
+func (ds *derived) ReadRawBlock(x, y int, box modis.Box) (*RawBuffer, error) {
+	buffer, err := ds.ReadBlock(x, y, box)
+	if err != nil {
+		return nil, err
+	}
+	return rawFromReadValues(ds.ImageParams(), buffer)
+}
+
+func (ds *derived) BlockSize() (int, int) {
+	return ds.bx, ds.by
+}
+
+func (ds *derived) Blocks(halo int) *BlockIterator {
+	return newBlockIterator(ds, halo)
+}
+
+// ToMemory reads the whole view into memory, returning nil if that fails.
+func (ds *derived) ToMemory() *inMemory {
+	p := ds.ImageParams()
+	res := NewInMemory(p.ToBuilder().Build())
+	for _, box := range blockBoxes(p, p.XSize(), memoryBlockLines) {
+		buffer, err := ds.block(box)
+		if err != nil {
+			return nil
+		}
+		if err = res.WriteBlock(0, 0, box, buffer); err != nil {
+			return nil
+		}
+	}
+	return res
+}
+
+func (ds *derived) Close() error {
+	return nil
+}
@@ -0,0 +1,149 @@
+package dataset_test
+
+import (
+	"math"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func testParams() *modis.ImageParams {
+	return modis.ImageParamsBuilder(8, 6).
+		Transform(modis.AffineTransform{1111950, 926.625433, 0, 7783653, 0, -926.625433}).
+		DataType(gdal.Int16).
+		Scale(0.02).
+		Offset(1).
+		NaN(-999).
+		Date(time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)).
+		Metadata("SOURCE", "test").
+		Build()
+}
+
+func writeTestData(t *testing.T, w dataset.Writer) {
+	for y := 0; y < w.ImageParams().YSize(); y++ {
+		for x := 0; x < w.ImageParams().XSize(); x++ {
+			v := math.NaN()
+			if x != y {
+				v = float64(x*10+y) * 0.02
+			}
+			if err := w.Write(x, y, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+// sameTransform compares transforms up to rounding of the coordinates written by the drivers.
+func sameTransform(a, b modis.AffineTransform) bool {
+	for i := range a {
+		if math.Abs(a[i]-b[i]) > 1e-6 {
+			return false
+		}
+	}
+	return true
+}
+
+// assertRoundTrip checks the image parameters and data read back from a written file, the
+// metadata item SOURCE is expected under metadataKey as some drivers prefix global attributes.
+func assertRoundTrip(t *testing.T, name string, expected *modis.ImageParams, r dataset.Reader, scaled bool, metadataKey string) {
+	actual := r.ImageParams()
+	if actual.XSize() != expected.XSize() || actual.YSize() != expected.YSize() {
+		t.Errorf("%s: expected size %dx%d, found %dx%d", name, expected.XSize(), expected.YSize(), actual.XSize(), actual.YSize())
+	}
+	if !sameTransform(actual.Transform(), expected.Transform()) {
+		t.Errorf("%s: expected transform %v, found %v", name, expected.Transform(), actual.Transform())
+	}
+	if actual.DataType() != expected.DataType() {
+		t.Errorf("%s: expected data type %s, found %s", name, expected.DataType().Name(), actual.DataType().Name())
+	}
+	from := gdal.CreateSpatialReference(expected.Projection())
+	defer from.Destroy()
+	to := gdal.CreateSpatialReference(actual.Projection())
+	defer to.Destroy()
+	if !from.IsSame(to) {
+		t.Errorf("%s: projection differs: %s", name, actual.Projection())
+	}
+	if nan, ok := actual.NaN(); !ok || nan != -999 {
+		t.Errorf("%s: expected NoData -999, found %v (%v)", name, nan, ok)
+	}
+	if scaled && (actual.Scale() != expected.Scale() || actual.Offset() != expected.Offset()) {
+		t.Errorf("%s: expected scale/offset %v/%v, found %v/%v", name, expected.Scale(), expected.Offset(), actual.Scale(), actual.Offset())
+	}
+	if actual.Metadata()[metadataKey] != "test" {
+		t.Errorf("%s: expected metadata %s=test, found %v", name, metadataKey, actual.Metadata())
+	}
+	buf, err := r.ReadBlock(0, 0, modis.Box{0, 0, expected.XSize(), expected.YSize()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < expected.YSize(); y++ {
+		for x := 0; x < expected.XSize(); x++ {
+			v := buf[y*expected.XSize()+x]
+			if x == y && !math.IsNaN(v) {
+				t.Errorf("%s: expected NaN at (%d,%d), found %v", name, x, y, v)
+			} else if x != y && math.Abs(v-float64(x*10+y)*0.02) > 1e-9 {
+				t.Errorf("%s: expected %v at (%d,%d), found %v", name, float64(x*10+y)*0.02, x, y, v)
+			}
+		}
+	}
+}
+
+func TestDrivers_RoundTrip(t *testing.T) {
+	cases := []struct {
+		driver      dataset.Driver
+		fileName    string
+		scaled      bool
+		metadataKey string
+	}{
+		{driver: dataset.GTiff, fileName: "gtiff.tif", scaled: true, metadataKey: "SOURCE"},
+		{driver: dataset.COG, fileName: "cog.tif", scaled: true, metadataKey: "SOURCE"},
+		// netCDF reports global attributes with the prefix of the variable they belong to
+		{driver: dataset.NetCDF, fileName: "netcdf.nc", scaled: true, metadataKey: "NC_GLOBAL#SOURCE"},
+		{driver: dataset.ENVI, fileName: "envi.bin", metadataKey: "SOURCE"}, // ENVI headers do not carry scale/offset reliably
+		{driver: dataset.VRT, fileName: "vrt.vrt", scaled: true, metadataKey: "SOURCE"},
+	}
+	dir := t.TempDir()
+	p := testParams()
+	for _, c := range cases {
+		fileName := path.Join(dir, c.fileName)
+		w, err := dataset.New(fileName, c.driver, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		writeTestData(t, w)
+		if err = w.Close(); err != nil {
+			t.Fatalf("%s: %v", c.driver, err)
+		}
+		r, err := dataset.Open(fileName)
+		if err != nil {
+			t.Fatalf("%s: %v", c.driver, err)
+		}
+		assertRoundTrip(t, string(c.driver), p, r, c.scaled, c.metadataKey)
+		r.Close()
+	}
+}
+
+func TestNew_CreateFails(t *testing.T) {
+	dir := path.Join(t.TempDir(), "missing")
+	for _, driver := range []dataset.Driver{dataset.GTiff, dataset.COG} {
+		if _, err := dataset.New(path.Join(dir, "out.tif"), driver, testParams(), nil); err == nil {
+			t.Errorf("%s: expected error creating a file in a missing directory", driver)
+		}
+	}
+}
+
+func TestDrivers_MEM(t *testing.T) {
+	p := testParams()
+	w, err := dataset.New("scratch", dataset.MEM, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	writeTestData(t, w)
+	// MEM datasets cannot be reopened by name, but the writer reads back as well
+	assertRoundTrip(t, "MEM", p, w.(dataset.Reader), true, "SOURCE")
+}
@@ -0,0 +1,234 @@
+package dataset
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// BlockFunc computes a block of output values from aligned input blocks. in holds the values of
+// the window (the box extended by the halo, NaN outside of the image) of every input reader in
+// the order of the readers, out must be filled for the box.
+type BlockFunc func(box modis.Box, in [][]float64, out []float64) error
+
+// PixelFunc computes an output value from the values of aligned inputs at one pixel.
+type PixelFunc func(values []float64) float64
+
+// Pixelwise converts a PixelFunc into a BlockFunc for inputs extended by halo pixels, which must
+// match ApplyOptions.Halo; inputs are cropped to the box.
+func Pixelwise(halo int, fn PixelFunc) BlockFunc {
+	return func(box modis.Box, in [][]float64, out []float64) error {
+		width := box[2] + 2*halo
+		size := width * (box[3] + 2*halo)
+		values := make([]float64, len(in))
+		for j, buf := range in {
+			if len(buf) != size {
+				return fmt.Errorf("input %d of %d values does not match box %v with halo %d", j, len(buf), box, halo)
+			}
+		}
+		for i := range out {
+			k := (i/box[2]+halo)*width + i%box[2] + halo
+			for j, buf := range in {
+				values[j] = buf[k]
+			}
+			out[i] = fn(values)
+		}
+		return nil
+	}
+}
+
+// ApplyOptions control the execution of Apply, the zero value (or nil) selects defaults.
+type ApplyOptions struct {
+	// Workers computing blocks in parallel, runtime.NumCPU() by default.
+	Workers int
+	// InFlight limits the number of blocks read but not yet written, 2*Workers by default.
+	InFlight int
+	// Halo extends input blocks by neighbouring pixels for neighbourhood operations.
+	Halo int
+	// BlockXSize and BlockYSize override the natural block size of the first input.
+	BlockXSize int
+	BlockYSize int
+}
+
+// Apply reads aligned inputs block by block, computes output blocks with fn in a pool of workers
+// and writes them to dst in block order. Memory is bounded by the number of blocks in flight.
+// Processing stops at the first error, which is returned, or when ctx is cancelled, in which case
+// ctx.Err() is returned. Values not representable in dst are counted by dst, see Overflows.
+func Apply(ctx context.Context, dst Writer, fn BlockFunc, opts *ApplyOptions, srcs ...Reader) error {
+	if len(srcs) == 0 {
+		return fmt.Errorf("no input readers")
+	}
+	params := []*modis.ImageParams{dst.ImageParams()}
+	for _, src := range srcs {
+		params = append(params, src.ImageParams())
+	}
+	if err := CheckAligned(params...); err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &ApplyOptions{}
+	}
+	workers := opts.Workers
+	if workers <= 0 {
+		workers = runtime.NumCPU()
+	}
+	inFlight := opts.InFlight
+	if inFlight <= 0 {
+		inFlight = 2 * workers
+	}
+	bx, by := srcs[0].BlockSize()
+	if opts.BlockXSize > 0 {
+		bx = opts.BlockXSize
+	}
+	if opts.BlockYSize > 0 {
+		by = opts.BlockYSize
+	}
+	boxes := blockBoxes(dst.ImageParams(), bx, by)
+	halo := opts.Halo
+
+	// all goroutines are stopped before returning, so that fn is never called after Apply returned
+	var wg sync.WaitGroup
+	ctx, cancel := context.WithCancel(ctx)
+	defer func() {
+		cancel()
+		wg.Wait()
+	}()
+
+	type job struct {
+		i   int
+		box modis.Box
+		in  [][]float64
+		out []float64
+		err error
+	}
+	tokens := make(chan struct{}, inFlight)
+	jobs := make(chan *job, workers)
+	results := make(chan *job, inFlight)
+
+	// reading is sequential as readers are not necessarily safe for concurrent use
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer close(jobs)
+		for i, box := range boxes {
+			select {
+			case tokens <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			j := &job{i: i, box: box}
+			window := modis.Box{box[0] - halo, box[1] - halo, box[2] + 2*halo, box[3] + 2*halo}
+			for _, src := range srcs {
+				var buf []float64
+				if buf, j.err = readWindow(src, window); j.err != nil {
+					break
+				}
+				j.in = append(j.in, buf)
+			}
+			if j.err == nil {
+				select {
+				case jobs <- j:
+					continue
+				case <-ctx.Done():
+					return
+				}
+			}
+			select {
+			case results <- j:
+			case <-ctx.Done():
+			}
+			return
+		}
+	}()
+
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				j.out = make([]float64, j.box[2]*j.box[3])
+				for k := range j.out {
+					j.out[k] = math.NaN()
+				}
+				j.err = fn(j.box, j.in, j.out)
+				j.in = nil
+				select {
+				case results <- j:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	pending := make(map[int]*job)
+	for next := 0; next < len(boxes); {
+		select {
+		case j := <-results:
+			if j.err != nil {
+				return j.err
+			}
+			pending[j.i] = j
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		for j, ok := pending[next]; ok; j, ok = pending[next] {
+			if err := dst.WriteBlock(0, 0, j.box, j.out); err != nil {
+				return err
+			}
+			delete(pending, next)
+			<-tokens
+			next++
+		}
+	}
+	return nil
+}
+
+// CheckAligned returns an error unless all image parameters describe the same grid: the same
+// size, transform and projection.
+func CheckAligned(params ...*modis.ImageParams) error {
+	for i := 1; i < len(params); i++ {
+		a, b := params[0], params[i]
+		if a.XSize() != b.XSize() || a.YSize() != b.YSize() {
+			return fmt.Errorf("grids not aligned: size %dx%d differs from %dx%d", b.XSize(), b.YSize(), a.XSize(), a.YSize())
+		}
+		if a.Transform() != b.Transform() {
+			return fmt.Errorf("grids not aligned: transform %v differs from %v", b.Transform(), a.Transform())
+		}
+		if a.Projection() != b.Projection() && !sameProjection(a.Projection(), b.Projection()) {
+			return fmt.Errorf("grids not aligned: projections differ")
+		}
+	}
+	return nil
+}
+
+func sameProjection(a, b string) bool {
+	sa := gdal.CreateSpatialReference(a)
+	defer sa.Destroy()
+	sb := gdal.CreateSpatialReference(b)
+	defer sb.Destroy()
+	return sa.IsSame(sb)
+}
+
+// blockBoxes splits the image into blocks of bx by by pixels in row-major order.
+func blockBoxes(p *modis.ImageParams, bx, by int) []modis.Box {
+	if bx <= 0 || bx > p.XSize() {
+		bx = p.XSize()
+	}
+	if by <= 0 || by > p.YSize() {
+		by = p.YSize()
+	}
+	var res []modis.Box
+	for y := 0; y < p.YSize(); y += by {
+		for x := 0; x < p.XSize(); x += bx {
+			res = append(res, modis.Box{x, y, num.MinInt(bx, p.XSize()-x), num.MinInt(by, p.YSize()-y)})
+		}
+	}
+	return res
+}
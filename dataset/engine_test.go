@@ -0,0 +1,124 @@
+package dataset_test
+
+import (
+	"context"
+	"errors"
+	"math"
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestApply_Pixelwise(t *testing.T) {
+	p := modis.ImageParamsBuilder(30, 200).Build()
+	a := dataset.NewInMemory(p)
+	fillSequence(t, a)
+	b := dataset.NewInMemory(p)
+	fillSequence(t, b)
+	dst, err := dataset.New(path.Join(t.TempDir(), "sum.tif"), dataset.GTiff, p.ToBuilder().DataType(gdal.Int32).Build(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	sum := func(values []float64) float64 {
+		return values[0] + 2*values[1]
+	}
+	// inputs extended by a halo are cropped
+	for _, halo := range []int{0, 2} {
+		opts := &dataset.ApplyOptions{Workers: 4, BlockYSize: 7, Halo: halo}
+		if err = dataset.Apply(context.Background(), dst, dataset.Pixelwise(halo, sum), opts, a, b); err != nil {
+			t.Fatal(err)
+		}
+		res, _ := dst.(dataset.Reader).ReadBlock(0, 0, modis.Box{0, 0, 30, 200})
+		for i, v := range res {
+			if v != float64(3*i) {
+				t.Fatalf("halo %d: expected %d at %d, found %v", halo, 3*i, i, v)
+			}
+		}
+	}
+	opts := &dataset.ApplyOptions{Halo: 1}
+	if err = dataset.Apply(context.Background(), dst, dataset.Pixelwise(0, sum), opts, a, b); err == nil {
+		t.Error("expected error for a halo not matching the options")
+	}
+}
+
+func TestApply_Halo(t *testing.T) {
+	p := modis.ImageParamsBuilder(20, 150).Build()
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	dst := dataset.NewInMemory(p)
+	// sum of the 3x3 neighbourhood ignoring pixels outside of the image
+	fn := func(box modis.Box, in [][]float64, out []float64) error {
+		w := box[2] + 2
+		for j := 0; j < box[3]; j++ {
+			for i := 0; i < box[2]; i++ {
+				s := 0.0
+				for dj := 0; dj < 3; dj++ {
+					for di := 0; di < 3; di++ {
+						if v := in[0][(j+dj)*w+i+di]; !math.IsNaN(v) {
+							s += v
+						}
+					}
+				}
+				out[j*box[2]+i] = s
+			}
+		}
+		return nil
+	}
+	if err := dataset.Apply(context.Background(), dst, fn, &dataset.ApplyOptions{Halo: 1, Workers: 3}, src); err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 150; y++ {
+		for x := 0; x < 20; x++ {
+			expected := 0.0
+			for yy := y - 1; yy <= y+1; yy++ {
+				for xx := x - 1; xx <= x+1; xx++ {
+					if xx >= 0 && yy >= 0 && xx < 20 && yy < 150 {
+						expected += float64(yy*20 + xx)
+					}
+				}
+			}
+			if v, _ := dst.Read(x, y); v != expected {
+				t.Fatalf("expected %v at (%d,%d), found %v", expected, x, y, v)
+			}
+		}
+	}
+}
+
+func TestApply_Errors(t *testing.T) {
+	p := modis.ImageParamsBuilder(10, 500).Build()
+	src := dataset.NewInMemory(p)
+	dst := dataset.NewInMemory(p)
+
+	failure := errors.New("failure")
+	calls := 0
+	fn := func(box modis.Box, in [][]float64, out []float64) error {
+		calls++
+		return failure
+	}
+	if err := dataset.Apply(context.Background(), dst, fn, &dataset.ApplyOptions{Workers: 1, BlockYSize: 1}, src); err != failure {
+		t.Errorf("expected failure, found %v", err)
+	}
+	if calls >= 500 {
+		t.Errorf("expected processing to stop early, found %d calls", calls)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	noop := func(box modis.Box, in [][]float64, out []float64) error { return nil }
+	if err := dataset.Apply(ctx, dst, noop, nil, src); err != context.Canceled {
+		t.Errorf("expected cancellation, found %v", err)
+	}
+
+	other := dataset.NewInMemory(modis.ImageParamsBuilder(10, 499).Build())
+	if err := dataset.Apply(context.Background(), dst, noop, nil, src, other); err == nil {
+		t.Error("expected alignment error")
+	}
+	shifted := dataset.NewInMemory(p.ToBuilder().Transform(modis.AffineTransform{1, 1, 0, 0, 0, 1}).Build())
+	if err := dataset.Apply(context.Background(), dst, noop, nil, shifted); err == nil {
+		t.Error("expected alignment error")
+	}
+}
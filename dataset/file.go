@@ -3,22 +3,40 @@ package dataset
 import (
 	"fmt"
 	"math"
+	"math/rand"
+	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/nordicsense/modis"
 	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis/internal/cpl"
 )
 
 type Driver string
 
 const (
 	GTiff Driver = "GTiff"
+	// COG (Cloud-Optimized GeoTIFF) cannot be written directly: data are written into a temporary
+	// GeoTIFF next to the target that is copied into the COG and removed on Close.
+	COG    Driver = "COG"
+	NetCDF Driver = "netCDF"
+	ENVI   Driver = "ENVI"
+	// MEM datasets live in memory only, the file name is ignored.
+	MEM Driver = "MEM"
+	// VRT datasets cannot hold data: data are written into a GeoTIFF with the name of the VRT file
+	// and ".tif" appended that is kept next to the VRT referencing it.
+	VRT Driver = "VRT"
 
-	band   = 1
-	bands  = 1
-	domain = ""
+	band  = 1
+	bands = 1
 )
 
+// Open opens a file for reading. The returned Reader wraps a single GDAL handle and is not
+// safe for concurrent use, see OpenConcurrent. The georeferencing of HDF-EOS grids is validated
+// against their StructMetadata, see WarningHandler. Metadata are read from the default and the
+// well-known domains (SUBDATASETS, IMAGE_STRUCTURE, RPC, GEOLOCATION) and, for files written by
+// New, from all domains and the band; the GDAL bindings cannot list other metadata.
 func Open(fileName string) (Reader, error) {
 	ds, err := gdal.Open(fileName, gdal.ReadOnly)
 	if err != nil {
@@ -43,47 +61,188 @@ func Open(fileName string) (Reader, error) {
 	if offset, ok := rb.GetOffset(); ok {
 		b = b.Offset(offset)
 	}
-	for _, k := range ds.Metadata(domain) {
-		b = b.Metadata(k, ds.MetadataItem(k, domain))
+	md, bandMD := readMetadata(&ds)
+	for domain, items := range md {
+		for k, v := range items {
+			b = b.DomainMetadata(domain, k, v)
+		}
+	}
+	for domain, items := range bandMD {
+		for k, v := range items {
+			b = b.BandDomainMetadata(domain, k, v)
+		}
 	}
-	return &imageFile{Dataset: ds, p: b.Build()}, nil
+	return &imageFile{Dataset: ds, p: georeference(fileName, b.Build())}, nil
 }
 
-func New(fileName string, driver Driver, p *modis.ImageParams) (Writer, error) {
+// New creates a new file with given driver and image parameters. Options may be nil for
+// driver defaults and are validated against the driver before the file is created.
+func New(fileName string, driver Driver, p *modis.ImageParams, opts *CreateOptions) (Writer, error) {
+	if err := opts.Validate(driver, p.DataType()); err != nil {
+		return nil, err
+	}
+	if err := checkNoData(p); err != nil {
+		return nil, err
+	}
 	gdalDriver, err := gdal.GetDriverByName(string(driver))
 	if err != nil {
 		return nil, err
 	}
-	ds := gdalDriver.Create(fileName, p.XSize(), p.YSize(), bands, p.DataType(), nil)
-	if err = ds.SetGeoTransform(p.Transform()); err != nil {
+	if err = opts.validateExtra(driver, gdalDriver.MetadataItem(gdal.DMD_CREATIONOPTIONLIST, "")); err != nil {
+		return nil, err
+	}
+	out := &output{fileName: fileName, onDisk: driver != MEM}
+	if opts != nil && opts.Atomic {
+		if out.atomicFileName, err = reserveFile(fileName); err != nil {
+			return nil, err
+		}
+	}
+	var w *imageFile
+	if capabilities[driver].createCopy {
+		w, err = newCreateCopy(fileName, out.writeFileName(), driver, gdalDriver, p, opts)
+	} else {
+		ds := gdalDriver.Create(out.writeFileName(), p.XSize(), p.YSize(), bands, p.DataType(), opts.toGDAL())
+		if ds == (gdal.Dataset{}) {
+			err = fmt.Errorf("failed to create %s with driver %s", out.writeFileName(), driver)
+		} else {
+			w, err = newImageFile(ds, p)
+		}
+	}
+	if err != nil {
+		if out.atomicFileName != "" {
+			_ = os.Remove(out.atomicFileName)
+		}
 		return nil, err
 	}
-	if err = ds.SetProjection(p.Projection()); err != nil {
+	w.out = out
+	return w, nil
+}
+
+// newCreateCopy creates an intermediate GeoTIFF next to fileName to write into for drivers
+// that only support CreateCopy; the target file is copied from it into copyFileName on Close.
+func newCreateCopy(fileName, copyFileName string, driver Driver, gdalDriver gdal.Driver, p *modis.ImageParams, opts *CreateOptions) (*imageFile, error) {
+	tiffDriver, err := gdal.GetDriverByName(string(GTiff))
+	if err != nil {
+		return nil, err
+	}
+	target := &copyTarget{fileName: copyFileName, tmpFileName: fileName + ".tmp.tif", driver: gdalDriver, options: opts.toGDAL()}
+	if driver == VRT {
+		target.tmpFileName = fileName + ".tif"
+		target.keep = true
+	}
+	ds := tiffDriver.Create(target.tmpFileName, p.XSize(), p.YSize(), bands, p.DataType(), nil)
+	if ds == (gdal.Dataset{}) {
+		return nil, fmt.Errorf("failed to create %s", target.tmpFileName)
+	}
+	w, err := newImageFile(ds, p)
+	if err != nil {
+		return nil, err
+	}
+	w.target = target
+	return w, nil
+}
+
+func newImageFile(ds gdal.Dataset, p *modis.ImageParams) (*imageFile, error) {
+	if err := ds.SetGeoTransform(p.Transform()); err != nil {
+		return nil, err
+	}
+	if err := ds.SetProjection(p.Projection()); err != nil {
 		return nil, err
 	}
 	rb := ds.RasterBand(band)
 	if nan, ok := p.NaN(); ok {
-		if err = rb.SetNoDataValue(nan); err != nil {
+		if err := rb.SetNoDataValue(nan); err != nil {
 			return nil, err
 		}
 	}
-	if err = rb.SetOffset(p.Offset()); err != nil {
+	if err := rb.SetOffset(p.Offset()); err != nil {
 		return nil, err
 	}
-	if err = rb.SetScale(p.Scale()); err != nil {
+	if err := rb.SetScale(p.Scale()); err != nil {
 		return nil, err
 	}
-	for k, v := range p.Metadata() {
-		if err := ds.SetMetadataItem(k, v, domain); err != nil {
-			return nil, err
-		}
+	if err := writeMetadata(&ds, p); err != nil {
+		return nil, err
 	}
 	return &imageFile{Dataset: ds, p: p}, nil
 }
 
 type imageFile struct {
 	gdal.Dataset
-	p *modis.ImageParams
+	p      *modis.ImageParams
+	target *copyTarget
+	out    *output
+	cache  *blockCache
+	// writeErr is the first error writing a block, failing Close.
+	writeErr error
+	// overflows counts values written that could not be represented.
+	overflows int
+}
+
+// reserveFile creates an empty file with a unique name next to fileName for GDAL to write into.
+// Unlike ioutil.TempFile it uses the usual mode, which the rename into place keeps.
+func reserveFile(fileName string) (string, error) {
+	dir, base := filepath.Split(fileName)
+	for i := 0; i < 10000; i++ {
+		name := filepath.Join(dir, fmt.Sprintf(".%s.%d.tmp", base, rand.Uint32()))
+		f, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE|os.O_EXCL, 0666)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return name, f.Close()
+	}
+	return "", fmt.Errorf("failed to reserve a temporary file for %s", fileName)
+}
+
+// output describes how a file created by New is completed on Close.
+type output struct {
+	fileName string
+	// atomicFileName is the temporary file renamed to fileName on success, empty if not atomic.
+	atomicFileName string
+	// onDisk is false for MEM datasets, which cannot be removed.
+	onDisk bool
+}
+
+func (o *output) writeFileName() string {
+	if o.atomicFileName != "" {
+		return o.atomicFileName
+	}
+	return o.fileName
+}
+
+// complete moves the written file into place in atomic mode, removing it if an earlier step
+// of Close failed.
+func (o *output) complete(err error) error {
+	if o.atomicFileName == "" {
+		return err
+	}
+	if err != nil {
+		_ = os.Remove(o.atomicFileName)
+		return err
+	}
+	return os.Rename(o.atomicFileName, o.fileName)
+}
+
+func (o *output) remove() error {
+	if !o.onDisk {
+		return nil
+	}
+	if err := os.Remove(o.writeFileName()); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// copyTarget describes the file to be created from the intermediate dataset on Close.
+type copyTarget struct {
+	fileName    string
+	tmpFileName string
+	driver      gdal.Driver
+	options     []string
+	keep        bool
 }
 
 func (ds *imageFile) ImageParams() *modis.ImageParams {
@@ -126,6 +285,13 @@ func (ds *imageFile) ReadTimeAtLatLon(ll modis.LatLon) (time.Time, error) {
 }
 
 func (ds *imageFile) ReadBlock(x, y int, box modis.Box) ([]float64, error) {
+	if ds.cache != nil {
+		return ds.readCached(x, y, box)
+	}
+	return ds.readBlock(x, y, box)
+}
+
+func (ds *imageFile) readBlock(x, y int, box modis.Box) ([]float64, error) {
 	rb := ds.Dataset.RasterBand(band) // Assume 1 band or panic
 	buffer := make([]float64, box[2]*box[3])
 	err := rb.IO(gdal.Read, x+box[0], y+box[1], box[2], box[3], buffer, box[2], box[3], 0, 0)
@@ -143,6 +309,26 @@ func (ds *imageFile) ReadBlock(x, y int, box modis.Box) ([]float64, error) {
 	return buffer, nil
 }
 
+func (ds *imageFile) ReadRawBlock(x, y int, box modis.Box) (*RawBuffer, error) {
+	rb := ds.Dataset.RasterBand(band) // Assume 1 band or panic
+	buffer, err := NewRawBuffer(ds.ImageParams().DataType(), box[2]*box[3])
+	if err != nil {
+		return nil, err
+	}
+	if err = rb.IO(gdal.Read, x+box[0], y+box[1], box[2], box[3], buffer.Data(), box[2], box[3], 0, 0); err != nil {
+		return nil, err
+	}
+	return buffer, nil
+}
+
+func (ds *imageFile) BlockSize() (int, int) {
+	return ds.Dataset.RasterBand(band).BlockSize()
+}
+
+func (ds *imageFile) Blocks(halo int) *BlockIterator {
+	return newBlockIterator(ds, halo)
+}
+
 func (ds *imageFile) ToMemory() *inMemory {
 	res := NewInMemory(ds.ImageParams().ToBuilder().Build())
 	// FIXME - copy data
@@ -158,32 +344,94 @@ func (ds *imageFile) WriteAtLatLon(ll modis.LatLon, v float64) error {
 	return ds.Write(x, y, v)
 }
 
+// WriteBlock converts values into the data type of the image reverting scale and offset and writes them.
+// NaN is written as the NoData value, integer values are rounded and finite values clamped to the range
+// of the type. Values that could not be represented are counted, see Overflows.
 func (ds *imageFile) WriteBlock(x, y int, box modis.Box, buffer []float64) error {
+	raw, overflows, err := rawFromValues(ds.ImageParams(), buffer)
+	if err != nil {
+		return err
+	}
+	if err = ds.WriteRawBlock(x, y, box, raw); err != nil {
+		return err
+	}
+	ds.overflows += overflows
+	return nil
+}
+
+// Overflows returns the number of values written by WriteBlock that could not be represented in
+// the data type of the image and were clamped (or, for NaN without NoData, written as 0).
+func (ds *imageFile) Overflows() int {
+	return ds.overflows
+}
+
+func (ds *imageFile) WriteRawBlock(x, y int, box modis.Box, buffer *RawBuffer) error {
+	if err := checkRawSize(box, buffer); err != nil {
+		return err
+	}
 	rb := ds.Dataset.RasterBand(band) // Assume 1 band or panic
-	// GDAL can handle any format, but it is more efficient to use specific type as we need to make a copy anyway
-	switch ds.ImageParams().DataType() {
-	case gdal.Int32:
-		data := make([]int32, len(buffer))
-		for i, v := range buffer {
-			data[i] = int32((v - ds.ImageParams().Offset()) / ds.ImageParams().Scale())
+	err := rb.IO(gdal.Write, x+box[0], y+box[1], box[2], box[3], buffer.Data(), box[2], box[3], 0, 0)
+	if err != nil && ds.writeErr == nil {
+		ds.writeErr = err
+	}
+	return err
+}
+
+// Close flushes and closes the file, for files created by New in atomic mode the file is moved
+// into place. An error is returned if any block failed to be written, GDAL failed to flush or
+// close the file or the target of COG and VRT files could not be created, atomic files are
+// removed in that case.
+func (ds *imageFile) Close() error {
+	return ds.close(false)
+}
+
+// Discard closes a file created by New and removes it instead of completing it, e.g. after a
+// failed computation, see the package function Discard.
+func (ds *imageFile) Discard() error {
+	return ds.close(true)
+}
+
+func (ds *imageFile) close(discard bool) error {
+	if ds.p == nil {
+		return nil
+	}
+	var err error
+	if ds.target != nil && !discard {
+		var copied gdal.Dataset
+		e := cpl.Do(func() {
+			ds.Dataset.FlushCache()
+			copied = ds.target.driver.CreateCopy(ds.target.fileName, ds.Dataset, 0, ds.target.options, nil, nil)
+			if copied != (gdal.Dataset{}) {
+				copied.Close()
+			}
+		})
+		switch {
+		case e != nil:
+			err = fmt.Errorf("failed to create %s: %v", ds.target.fileName, e)
+		case copied == (gdal.Dataset{}):
+			err = fmt.Errorf("failed to create %s", ds.target.fileName)
 		}
-		return rb.IO(gdal.Write, x+box[0], y+box[1], box[2], box[3], data, box[2], box[3], 0, 0)
-	case gdal.Float32:
-		data := make([]float32, len(buffer))
-		for i, v := range buffer {
-			data[i] = float32((v - ds.ImageParams().Offset()) / ds.ImageParams().Scale())
+	}
+	if e := cpl.Do(ds.Dataset.Close); e != nil && err == nil && ds.out != nil && !discard {
+		err = fmt.Errorf("failed to write %s: %v", ds.out.fileName, e)
+	}
+	if ds.target != nil && (!ds.target.keep || discard) {
+		if e := os.Remove(ds.target.tmpFileName); e != nil && !os.IsNotExist(e) && err == nil {
+			err = e
 		}
-		return rb.IO(gdal.Write, x+box[0], y+box[1], box[2], box[3], data, box[2], box[3], 0, 0)
-	default: // treat as float64
-		data := make([]float64, len(buffer))
-		for i, v := range buffer {
-			data[i] = (v - ds.ImageParams().Offset()) / ds.ImageParams().Scale()
+	}
+	if ds.out != nil {
+		switch {
+		case discard:
+			if e := ds.out.remove(); e != nil && err == nil {
+				err = e
+			}
+		case ds.writeErr != nil:
+			err = ds.out.complete(fmt.Errorf("failed to write %s: %v", ds.out.fileName, ds.writeErr))
+		default:
+			err = ds.out.complete(err)
 		}
-		return rb.IO(gdal.Write, x+box[0], y+box[1], box[2], box[3], data, box[2], box[3], 0, 0)
 	}
-}
-
-func (ds *imageFile) Close() {
-	ds.Dataset.Close()
 	ds.p = nil
+	return err
 }
@@ -0,0 +1,283 @@
+package dataset_test
+
+import (
+	"io/ioutil"
+	"math"
+	"os"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestImageFile_WriteBlock_DataTypes(t *testing.T) {
+	values := []float64{math.NaN(), 1.26, -3.5, 1e12}
+	cases := []struct {
+		dt        gdal.DataType
+		expected  []float64
+		overflows int
+	}{
+		{dt: gdal.Byte, expected: []float64{255, 1, 0, 255}, overflows: 2},
+		{dt: gdal.Int16, expected: []float64{255, 1, -4, math.MaxInt16}, overflows: 1},
+		{dt: gdal.UInt16, expected: []float64{255, 1, 0, math.MaxUint16}, overflows: 2},
+		{dt: gdal.Int32, expected: []float64{255, 1, -4, math.MaxInt32}, overflows: 1},
+		{dt: gdal.UInt32, expected: []float64{255, 1, 0, math.MaxUint32}, overflows: 2},
+		{dt: gdal.Float32, expected: []float64{255, float64(float32(1.26)), -3.5, float64(float32(1e12))}, overflows: 0},
+		{dt: gdal.Float64, expected: []float64{255, 1.26, -3.5, 1e12}, overflows: 0},
+	}
+	dir := t.TempDir()
+	for _, c := range cases {
+		p := modis.ImageParamsBuilder(2, 2).DataType(c.dt).NaN(255).Build()
+		w, err := dataset.New(path.Join(dir, c.dt.Name()+".tif"), dataset.GTiff, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = w.WriteBlock(0, 0, modis.Box{0, 0, 2, 2}, values); err != nil {
+			t.Errorf("%s: unexpected error %v", c.dt.Name(), err)
+		}
+		if n := dataset.Overflows(w); n != c.overflows {
+			t.Errorf("%s: expected %d overflows, found %d", c.dt.Name(), c.overflows, n)
+		}
+		raw, err := w.(dataset.Reader).ReadRawBlock(0, 0, modis.Box{0, 0, 2, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		for i, expected := range c.expected {
+			if raw.At(i) != expected {
+				t.Errorf("%s: expected %v at %d, found %v", c.dt.Name(), expected, i, raw.At(i))
+			}
+		}
+		w.Close()
+	}
+}
+
+func TestImageFile_WriteBlock_NaNWithoutNoData(t *testing.T) {
+	p := modis.ImageParamsBuilder(1, 1).DataType(gdal.Int32).Build()
+	w, err := dataset.New(path.Join(t.TempDir(), "nonan.tif"), dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = w.Write(0, 0, math.NaN()); err != nil {
+		t.Fatal(err)
+	}
+	if n := dataset.Overflows(w); n != 1 {
+		t.Errorf("expected NaN to be counted, found %d overflows", n)
+	}
+}
+
+func TestImageFile_WriteBlock_Float32Inf(t *testing.T) {
+	p := modis.ImageParamsBuilder(2, 1).DataType(gdal.Float32).Build()
+	w, err := dataset.New(path.Join(t.TempDir(), "inf.tif"), dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = w.WriteBlock(0, 0, modis.Box{0, 0, 2, 1}, []float64{math.Inf(1), math.Inf(-1)}); err != nil {
+		t.Fatal(err)
+	}
+	if n := dataset.Overflows(w); n != 0 {
+		t.Errorf("expected no overflows, found %d", n)
+	}
+	values, err := w.(dataset.Reader).ReadBlock(0, 0, modis.Box{0, 0, 2, 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !math.IsInf(values[0], 1) || !math.IsInf(values[1], -1) {
+		t.Errorf("expected infinities, found %v", values)
+	}
+}
+
+func TestImageFile_WriteBlock_NoData(t *testing.T) {
+	dir := t.TempDir()
+	for _, p := range []*modis.ImageParams{
+		modis.ImageParamsBuilder(1, 1).DataType(gdal.Byte).NaN(-999).Build(),
+		modis.ImageParamsBuilder(1, 1).DataType(gdal.Int16).NaN(0.5).Build(),
+		modis.ImageParamsBuilder(1, 1).DataType(gdal.Int32).NaN(math.NaN()).Build(),
+		modis.ImageParamsBuilder(1, 1).DataType(gdal.Float32).NaN(0.1).Build(),
+	} {
+		nan, _ := p.NaN()
+		if _, err := dataset.New(path.Join(dir, "invalid.tif"), dataset.GTiff, p, nil); err == nil {
+			t.Errorf("%s: expected error for NoData %v", p.DataType().Name(), nan)
+		}
+	}
+
+	p := modis.ImageParamsBuilder(3, 1).DataType(gdal.Int16).NaN(-999).Build()
+	w, err := dataset.New(path.Join(dir, "collision.tif"), dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = w.WriteBlock(0, 0, modis.Box{0, 0, 3, 1}, []float64{math.NaN(), -999.2, 1e6}); err != nil {
+		t.Fatal(err)
+	}
+	// the valid value rounding to NoData and the clamped one are counted once each
+	if n := dataset.Overflows(w); n != 2 {
+		t.Errorf("expected 2 overflows, found %d", n)
+	}
+}
+
+// GDAL lists metadata items as KEY=VALUE, Open splits them at the first "=".
+func TestOpen_MetadataItems(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "items.tif")
+	p := modis.ImageParamsBuilder(1, 1).
+		Metadata("EQUATION", "LST=0.02*DN").
+		Metadata("EMPTY", "").
+		Build()
+	w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	expected := map[string]string{"EQUATION": "LST=0.02*DN", "EMPTY": ""}
+	if actual := r.ImageParams().Metadata(); !reflect.DeepEqual(actual, expected) {
+		t.Errorf("expected %v, found %v", expected, actual)
+	}
+}
+
+func TestImageFile_MetadataRoundTrip(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "metadata.tif")
+	p := modis.ImageParamsBuilder(3, 2).
+		Metadata("RANGEBEGINNINGDATE", "2020-07-01").
+		DomainMetadata("PROVENANCE", "source", "MOD11A1.A2020183.h19v02.061.hdf").
+		BandMetadata("long_name", "Daily daytime 1km grid Land-surface Temperature").
+		BandDomainMetadata("QA", "spec", "MOD11 QC").
+		Build()
+	w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	actual := r.ImageParams()
+	for _, domain := range []string{"", "PROVENANCE"} {
+		if !reflect.DeepEqual(actual.DomainMetadata(domain), p.DomainMetadata(domain)) {
+			t.Errorf("domain %q: expected %v, found %v", domain, p.DomainMetadata(domain), actual.DomainMetadata(domain))
+		}
+	}
+	for _, domain := range []string{"", "QA"} {
+		if !reflect.DeepEqual(actual.BandDomainMetadata(domain), p.BandDomainMetadata(domain)) {
+			t.Errorf("band domain %q: expected %v, found %v", domain, p.BandDomainMetadata(domain), actual.BandDomainMetadata(domain))
+		}
+	}
+}
+
+func TestOpen_KnownMetadataDomains(t *testing.T) {
+	// a file written outside of New has no bookkeeping items listing its domains
+	fileName := path.Join(t.TempDir(), "foreign.tif")
+	driver, err := gdal.GetDriverByName("GTiff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ds := driver.Create(fileName, 2, 2, 1, gdal.Float32, nil)
+	_ = ds.SetMetadataItem("SUBDATASET_1_NAME", `HDF4_EOS:EOS_GRID:"a.hdf":grid:LST_Day_1km`, "SUBDATASETS")
+	_ = ds.SetMetadataItem("LINE_OFF", "10", "RPC")
+	_ = ds.SetMetadataItem("source", "a.hdf", "PROVENANCE")
+	ds.Close()
+
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := r.ImageParams()
+	r.Close()
+	if v := p.DomainMetadata("SUBDATASETS")["SUBDATASET_1_NAME"]; v == "" {
+		t.Error("expected SUBDATASETS to be read")
+	}
+	if v := p.DomainMetadata("RPC")["LINE_OFF"]; v != "10" {
+		t.Errorf("expected RPC to be read, found %q", v)
+	}
+	if md := p.DomainMetadata("PROVENANCE"); len(md) != 0 {
+		t.Errorf("expected unknown domains of foreign files not to be read, found %v", md)
+	}
+
+	// source domains are not written, well-known ones are read without bookkeeping items
+	fileName = path.Join(t.TempDir(), "copy.tif")
+	w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if r, err = dataset.Open(fileName); err != nil {
+		t.Fatal(err)
+	}
+	p = r.ImageParams()
+	r.Close()
+	if md := p.DomainMetadata("SUBDATASETS"); len(md) != 0 {
+		t.Errorf("expected SUBDATASETS not to be written, found %v", md)
+	}
+	if v := p.DomainMetadata("RPC")["LINE_OFF"]; v != "10" {
+		t.Errorf("expected RPC to round-trip, found %q", v)
+	}
+	if md := p.Metadata(); len(md) != 0 {
+		t.Errorf("expected no bookkeeping items in metadata, found %v", md)
+	}
+}
+
+func TestNew_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	p := modis.ImageParamsBuilder(4, 3).Build()
+	opts := &dataset.CreateOptions{Atomic: true}
+
+	fileName := path.Join(dir, "complete.tif")
+	w, err := dataset.New(fileName, dataset.GTiff, p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestData(t, w)
+	if _, err = os.Stat(fileName); !os.IsNotExist(err) {
+		t.Errorf("expected no file before Close, found %v", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = r.Close()
+	plain := path.Join(t.TempDir(), "plain.tif")
+	if w, err = dataset.New(plain, dataset.GTiff, p, nil); err != nil {
+		t.Fatal(err)
+	}
+	_ = w.Close()
+	atomicInfo, _ := os.Stat(fileName)
+	plainInfo, _ := os.Stat(plain)
+	if atomicInfo.Mode() != plainInfo.Mode() {
+		t.Errorf("expected mode %v of atomic file, found %v", plainInfo.Mode(), atomicInfo.Mode())
+	}
+
+	w, err = dataset.New(path.Join(dir, "discarded.tif"), dataset.GTiff, p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestData(t, w)
+	if err = dataset.Discard(w); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 1 || files[0].Name() != "complete.tif" {
+		var names []string
+		for _, f := range files {
+			names = append(names, f.Name())
+		}
+		t.Errorf("expected only complete.tif, found %v", names)
+	}
+
+	if _, err = dataset.New(path.Join(dir, "atomic.envi"), dataset.ENVI, p, opts); err == nil {
+		t.Error("expected error for atomic ENVI")
+	}
+}
@@ -0,0 +1,144 @@
+package dataset
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/hdfeos"
+)
+
+// WarningHandler, if set, receives warnings about inconsistencies found by Open that do not
+// prevent reading, e.g. a GDAL geotransform overridden by the grid definition in StructMetadata.
+// Warnings are dropped by default.
+var WarningHandler func(fileName, message string)
+
+// hdfMetadataFiles bounds the number of HDF files whose metadata is kept by hdfMetadata.
+const hdfMetadataFiles = 16
+
+// hdfMetadata keeps the metadata of recently read HDF files, so that opening several
+// subdatasets or handles of a file, e.g. by OpenConcurrent or OpenMasked, parses it once.
+var hdfMetadata = &metadataCache{lru: list.New(), entries: make(map[string]*list.Element)}
+
+type metadataCache struct {
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[string]*list.Element
+}
+
+type metadataEntry struct {
+	fileName string
+	size     int64
+	modTime  time.Time
+	md       *hdfeos.Metadata
+}
+
+// read returns the metadata of an HDF file, read again if the file changed since it was cached.
+func (c *metadataCache) read(fileName string) (*hdfeos.Metadata, error) {
+	fi, err := os.Stat(fileName)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if e, ok := c.entries[fileName]; ok {
+		entry := e.Value.(*metadataEntry)
+		if entry.size == fi.Size() && entry.modTime.Equal(fi.ModTime()) {
+			c.lru.MoveToFront(e)
+			return entry.md, nil
+		}
+		c.lru.Remove(e)
+		delete(c.entries, fileName)
+	}
+	md, err := hdfeos.ReadFile(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if c.lru.Len() >= hdfMetadataFiles {
+		delete(c.entries, c.lru.Remove(c.lru.Back()).(*metadataEntry).fileName)
+	}
+	c.entries[fileName] = c.lru.PushFront(&metadataEntry{fileName: fileName, size: fi.Size(), modTime: fi.ModTime(), md: md})
+	return md, nil
+}
+
+// georeference validates the transform and projection reported by GDAL against the grid
+// definition in the StructMetadata of HDF-EOS files and overrides them where they disagree,
+// e.g. where GDAL reports an identity transform for HDF4 subdatasets. StructMetadata is read
+// from the HDF file of subdatasets (GDAL does not expose it) and from the metadata items of
+// other files.
+func georeference(fileName string, p *modis.ImageParams) *modis.ImageParams {
+	warn := func(format string, args ...interface{}) {
+		if WarningHandler != nil {
+			WarningHandler(fileName, fmt.Sprintf(format, args...))
+		}
+	}
+	var md *hdfeos.Metadata
+	var err error
+	name := ""
+	if sub, ok := hdfeos.ParseSubdataset(fileName); ok {
+		name = sub.Grid
+		md, err = hdfMetadata.read(sub.File)
+	} else {
+		md, err = hdfeos.FromMetadata(p.Metadata())
+	}
+	if err != nil {
+		warn("cannot read HDF-EOS metadata: %v", err)
+		return p
+	}
+	if md.Struct == nil {
+		return p
+	}
+	if grids, err := md.Grids(); name == "" && err == nil && len(grids) != 1 {
+		return p // a file with several grids rather than a subdataset of one
+	}
+	grid, err := md.Grid(name)
+	if err != nil {
+		warn("cannot georeference from StructMetadata: %v", err)
+		return p
+	}
+	if grid.XDim != p.XSize() || grid.YDim != p.YSize() {
+		warn("size %dx%d differs from %dx%d of grid %s in StructMetadata", p.XSize(), p.YSize(), grid.XDim, grid.YDim, grid.Name)
+		return p
+	}
+	at, err := grid.Transform()
+	if err != nil {
+		warn("cannot georeference from StructMetadata: %v", err)
+		return p
+	}
+	b := p.ToBuilder()
+	if !sameTransform(p.Transform(), at, p.XSize(), p.YSize()) {
+		warn("geotransform %v differs from %v of grid %s in StructMetadata, using the latter", p.Transform(), at, grid.Name)
+		b = b.Transform(at)
+	}
+	wkt, err := gridWKT(grid.Projection)
+	if err != nil {
+		warn("cannot validate projection: %v", err)
+	} else if wkt != "" && (p.Projection() == "" || !sameProjection(p.Projection(), wkt)) {
+		warn("projection differs from %s of grid %s in StructMetadata, using the latter", grid.Projection, grid.Name)
+		b = b.Projection(wkt)
+	}
+	return b.Build()
+}
+
+// gridWKT returns the WKT of a GCTP projection, empty for projections that are not validated.
+func gridWKT(projection string) (string, error) {
+	switch projection {
+	case "GCTP_SNSOID":
+		return modis.ModisWKT, nil
+	case "GCTP_GEO":
+		return wgs84WKT()
+	}
+	return "", nil
+}
+
+// sameTransform compares transforms within gridTolerance pixels across the whole grid.
+func sameTransform(a, b modis.AffineTransform, xSize, ySize int) bool {
+	px, py := math.Abs(b[1])*gridTolerance, math.Abs(b[5])*gridTolerance
+	return math.Abs(a[0]-b[0]) <= px && math.Abs(a[3]-b[3]) <= py &&
+		math.Abs(a[1]-b[1])*float64(xSize) <= px && math.Abs(a[2]-b[2])*float64(ySize) <= px &&
+		math.Abs(a[4]-b[4])*float64(xSize) <= py && math.Abs(a[5]-b[5])*float64(ySize) <= py
+}
@@ -0,0 +1,102 @@
+package dataset_test
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"testing"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/hdfeos"
+)
+
+const structMetadata = `GROUP=GridStructure
+	GROUP=GRID_1
+		GridName="MODIS_Grid_Daily_1km_LST"
+		XDim=4
+		YDim=3
+		UpperLeftPointMtrs=(1111950.519667,7783653.637667)
+		LowerRightMtrs=(1115657.021399,7780873.761368)
+		Projection=GCTP_SNSOID
+		ProjParams=(6371007.181000,0,0,0,0,0,0,0,0,0,0,0,0)
+		SphereCode=-1
+		GridOrigin=HDFE_GD_UL
+	END_GROUP=GRID_1
+END_GROUP=GridStructure
+END
+`
+
+func TestOpen_Georeference(t *testing.T) {
+	var warnings []string
+	handler := dataset.WarningHandler
+	dataset.WarningHandler = func(fileName, message string) { warnings = append(warnings, message) }
+	defer func() { dataset.WarningHandler = handler }()
+
+	expected := modis.AffineTransform{1111950.519667, 926.625433, 0, 7783653.637667, 0, -926.625433}
+	for name, at := range map[string]modis.AffineTransform{
+		"identity":   {0, 1, 0, 0, 0, 1},
+		"consistent": expected,
+	} {
+		warnings = nil
+		fileName := path.Join(t.TempDir(), name+".tif")
+		p := modis.ImageParamsBuilder(4, 3).Transform(at).Metadata("StructMetadata.0", structMetadata).Build()
+		w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Close()
+		r, err := dataset.Open(fileName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		actual := r.ImageParams().Transform()
+		r.Close()
+		for i := range actual {
+			if d := actual[i] - expected[i]; d > 1e-6 || d < -1e-6 {
+				t.Errorf("%s: expected transform %v, found %v", name, expected, actual)
+				break
+			}
+		}
+		if name == "identity" && (len(warnings) != 1 || !strings.Contains(warnings[0], "geotransform")) {
+			t.Errorf("expected geotransform warning, found %v", warnings)
+		}
+		if name == "consistent" && len(warnings) != 0 {
+			t.Errorf("expected no warnings, found %v", warnings)
+		}
+	}
+}
+
+// Set MODIS_HDF_FIXTURE to an HDF-EOS granule, e.g. a MOD11A1 file, to georeference its subdatasets.
+func TestOpen_GeoreferenceGranule(t *testing.T) {
+	fileName := os.Getenv("MODIS_HDF_FIXTURE")
+	if fileName == "" {
+		t.Skip("MODIS_HDF_FIXTURE not set")
+	}
+	md, err := hdfeos.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	grids, err := md.Grids()
+	if err != nil || len(grids) == 0 || len(grids[0].Fields) == 0 {
+		t.Fatalf("expected grids with fields in StructMetadata, found %v (%v)", grids, err)
+	}
+	grid := grids[0]
+	expected, err := grid.Transform()
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, err := dataset.Open(fmt.Sprintf(`HDF4_EOS:EOS_GRID:"%s":%s:%s`, fileName, grid.Name, grid.Fields[0]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	actual := r.ImageParams().Transform()
+	for i := range actual {
+		if d := actual[i] - expected[i]; d > 1e-6 || d < -1e-6 {
+			t.Errorf("expected transform %v, found %v", expected, actual)
+			break
+		}
+	}
+}
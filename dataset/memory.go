@@ -72,6 +72,30 @@ func (ds *inMemory) ReadBlock(x, y int, box modis.Box) ([]float64, error) {
 	return buffer, nil
 }
 
+func (ds *inMemory) ReadRawBlock(x, y int, box modis.Box) (*RawBuffer, error) {
+	buffer, err := ds.ReadBlock(x, y, box)
+	if err != nil {
+		return nil, err
+	}
+	return rawFromReadValues(ds.ImageParams(), buffer)
+}
+
+func (ds *inMemory) BlockSize() (int, int) {
+	return ds.ImageParams().XSize(), memoryBlockLines
+}
+
+func (ds *inMemory) Blocks(halo int) *BlockIterator {
+	return newBlockIterator(ds, halo)
+}
+
+func (ds *inMemory) ToMemory() *inMemory {
+	res := NewInMemory(ds.ImageParams().ToBuilder().Build())
+	for i, row := range ds.data {
+		copy(res.data[i], row)
+	}
+	return res
+}
+
 func (ds *inMemory) Write(x, y int, v float64) error {
 	return ds.WriteBlock(x, y, modis.Box{0, 0, 1, 1}, []float64{v})
 }
@@ -90,6 +114,18 @@ func (ds *inMemory) WriteBlock(x, y int, box modis.Box, buffer []float64) error
 	return nil
 }
 
+func (ds *inMemory) WriteRawBlock(x, y int, box modis.Box, buffer *RawBuffer) error {
+	if err := checkRawSize(box, buffer); err != nil {
+		return err
+	}
+	return ds.WriteBlock(x, y, box, valuesFromRaw(ds.ImageParams(), buffer))
+}
+
+func (ds *inMemory) Close() error {
+	ds.data = nil
+	return nil
+}
+
 func (ds *inMemory) ToFileWriter(fileName string, driver Driver) (Writer, error) {
 	// FIXME - implement
 	return nil, fmt.Errorf("not implemented")
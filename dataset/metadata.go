@@ -0,0 +1,128 @@
+package dataset
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+)
+
+// The GDAL bindings cannot list metadata domains nor band metadata items. Open therefore reads
+// the default domain, the well-known domains below and the domains and band items that New
+// records in the bookkeeping items. Other domains and band metadata of files not written by New
+// are not read and do not survive a conversion.
+const (
+	// metadataDomainsItem lists the domains of dataset metadata other than the default and the
+	// well-known ones. It is written by New and hidden by Open.
+	metadataDomainsItem = "MODIS_METADATA_DOMAINS"
+	// bandMetadataItem lists the keys of band metadata by domain. It is written by New and
+	// hidden by Open.
+	bandMetadataItem = "MODIS_BAND_METADATA"
+)
+
+// knownDomains are the dataset metadata domains Open reads by name from any file.
+var knownDomains = []string{"SUBDATASETS", "IMAGE_STRUCTURE", "RPC", "GEOLOCATION"}
+
+// sourceDomains describe the layout of the file they are read from, New does not write them.
+var sourceDomains = map[string]bool{"SUBDATASETS": true, "IMAGE_STRUCTURE": true}
+
+func isKnownDomain(domain string) bool {
+	for _, d := range knownDomains {
+		if d == domain {
+			return true
+		}
+	}
+	return false
+}
+
+func isBookkeepingItem(key string) bool {
+	return key == metadataDomainsItem || key == bandMetadataItem
+}
+
+// readMetadata reads dataset metadata of the default domain, of the well-known domains and of
+// the domains listed by New as well as the band metadata listed by New, keyed by domain. GDAL
+// lists dataset items as KEY=VALUE, they are split at the first "=" and items without one are
+// skipped.
+func readMetadata(ds *gdal.Dataset) (map[string]map[string]string, map[string]map[string]string) {
+	domains := append([]string{""}, knownDomains...)
+	var listed []string
+	if err := json.Unmarshal([]byte(ds.MetadataItem(metadataDomainsItem, "")), &listed); err == nil {
+		for _, domain := range listed {
+			if domain != "" && !isKnownDomain(domain) {
+				domains = append(domains, domain)
+			}
+		}
+	}
+	md := make(map[string]map[string]string)
+	for _, domain := range domains {
+		for _, kv := range ds.Metadata(domain) {
+			if parts := strings.SplitN(kv, "=", 2); len(parts) == 2 && !isBookkeepingItem(parts[0]) {
+				if md[domain] == nil {
+					md[domain] = make(map[string]string)
+				}
+				md[domain][parts[0]] = parts[1]
+			}
+		}
+	}
+	bandMD := make(map[string]map[string]string)
+	rb := ds.RasterBand(band)
+	var keys map[string][]string
+	if err := json.Unmarshal([]byte(rb.MetadataItem(bandMetadataItem, "")), &keys); err == nil {
+		for domain, names := range keys {
+			bandMD[domain] = make(map[string]string)
+			for _, name := range names {
+				bandMD[domain][name] = rb.MetadataItem(name, domain)
+			}
+		}
+	}
+	return md, bandMD
+}
+
+// writeMetadata writes dataset and band metadata of all domains but the source ones along with
+// the items listing the domains and band items Open cannot find otherwise.
+func writeMetadata(ds *gdal.Dataset, p *modis.ImageParams) error {
+	var listed []string
+	for _, domain := range p.MetadataDomains() {
+		if sourceDomains[domain] {
+			continue
+		}
+		for k, v := range p.DomainMetadata(domain) {
+			if isBookkeepingItem(k) {
+				continue
+			}
+			if err := ds.SetMetadataItem(k, v, domain); err != nil {
+				return err
+			}
+		}
+		if domain != "" && !isKnownDomain(domain) {
+			listed = append(listed, domain)
+		}
+	}
+	if len(listed) > 0 {
+		data, _ := json.Marshal(listed)
+		if err := ds.SetMetadataItem(metadataDomainsItem, string(data), ""); err != nil {
+			return err
+		}
+	}
+	rb := ds.RasterBand(band)
+	keys := make(map[string][]string)
+	for _, domain := range p.BandMetadataDomains() {
+		for k, v := range p.BandDomainMetadata(domain) {
+			if isBookkeepingItem(k) {
+				continue
+			}
+			if err := rb.SetMetadataItem(k, v, domain); err != nil {
+				return err
+			}
+			keys[domain] = append(keys[domain], k)
+		}
+		sort.Strings(keys[domain])
+	}
+	if len(keys) > 0 {
+		data, _ := json.Marshal(keys)
+		return rb.SetMetadataItem(bandMetadataItem, string(data), "")
+	}
+	return nil
+}
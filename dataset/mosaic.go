@@ -0,0 +1,245 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// MosaicPolicy defines which value is taken where mosaicked images overlap.
+type MosaicPolicy int
+
+const (
+	// MosaicFirst takes the first valid value in the order of the sources.
+	MosaicFirst MosaicPolicy = iota
+	// MosaicLast takes the last valid value in the order of the sources.
+	MosaicLast
+	MosaicMean
+	MosaicMax
+	MosaicMin
+	// MosaicBestQuality takes the valid value with the lowest (best) value of the
+	// corresponding quality layer, as for MODIS QC flags where 0 means best quality.
+	MosaicBestQuality
+)
+
+const (
+	mosaicBlockLines = 64
+	// gridTolerance defines the tolerance of grid alignment as a fraction of the pixel size.
+	gridTolerance = 1e-3
+)
+
+// MosaicOptions define the overlap policy of a mosaic, Quality gives a quality layer per
+// source (in the same order and on the same grid) for MosaicBestQuality.
+type MosaicOptions struct {
+	Policy  MosaicPolicy
+	Quality []Reader
+}
+
+// MosaicParams derives parameters of an image covering the union extent of images on the
+// same grid (e.g. adjacent MODIS tiles). All other parameters are copied from the first one.
+func MosaicParams(params ...*modis.ImageParams) (*modis.ImageParams, error) {
+	if len(params) == 0 {
+		return nil, fmt.Errorf("no images to mosaic")
+	}
+	ref := params[0]
+	x0, y0, x1, y1 := 0, 0, ref.XSize(), ref.YSize()
+	for _, p := range params[1:] {
+		ox, oy, err := gridOffset(ref, p)
+		if err != nil {
+			return nil, err
+		}
+		x0, y0 = num.MinInt(x0, ox), num.MinInt(y0, oy)
+		x1, y1 = num.MaxInt(x1, ox+p.XSize()), num.MaxInt(y1, oy+p.YSize())
+	}
+	at := ref.Transform()
+	return ref.ToBuilder().
+		Size(x1-x0, y1-y0).
+		Transform(modis.AffineTransform{at[0] + float64(x0)*at[1], at[1], 0, at[3] + float64(y0)*at[5], 0, at[5]}).
+		Build(), nil
+}
+
+// Mosaic writes srcs into dst according to the policy (MosaicFirst for nil options). All
+// images must be on the same grid; parts of sources outside of dst are ignored and pixels
+// of dst without valid source values, e.g. for missing tiles, are set to NaN.
+func Mosaic(dst Writer, srcs []Reader, opts *MosaicOptions) error {
+	if opts == nil {
+		opts = &MosaicOptions{}
+	}
+	dp := dst.ImageParams()
+	if opts.Policy == MosaicBestQuality && len(opts.Quality) != len(srcs) {
+		return fmt.Errorf("expected %d quality layers, found %d", len(srcs), len(opts.Quality))
+	}
+	offsets := make([][2]int, len(srcs))
+	for i, src := range srcs {
+		ox, oy, err := gridOffset(dp, src.ImageParams())
+		if err != nil {
+			return err
+		}
+		offsets[i] = [2]int{ox, oy}
+		if opts.Policy == MosaicBestQuality {
+			if err = CheckAligned(src.ImageParams(), opts.Quality[i].ImageParams()); err != nil {
+				return err
+			}
+		}
+	}
+	for _, box := range blockBoxes(dp, dp.XSize(), mosaicBlockLines) {
+		acc := newMosaicAccumulator(box[2]*box[3], opts.Policy)
+		for i, src := range srcs {
+			sp := src.ImageParams()
+			// intersection in the coordinates of dst
+			x0, y0 := num.MaxInt(box[0], offsets[i][0]), num.MaxInt(box[1], offsets[i][1])
+			x1 := num.MinInt(box[0]+box[2], offsets[i][0]+sp.XSize())
+			y1 := num.MinInt(box[1]+box[3], offsets[i][1]+sp.YSize())
+			if x1 <= x0 || y1 <= y0 {
+				continue
+			}
+			sbox := modis.Box{x0 - offsets[i][0], y0 - offsets[i][1], x1 - x0, y1 - y0}
+			values, err := src.ReadBlock(0, 0, sbox)
+			if err != nil {
+				return err
+			}
+			var quality []float64
+			if opts.Policy == MosaicBestQuality {
+				if quality, err = opts.Quality[i].ReadBlock(0, 0, sbox); err != nil {
+					return err
+				}
+			}
+			for j := 0; j < sbox[3]; j++ {
+				for k := 0; k < sbox[2]; k++ {
+					idx := (y0-box[1]+j)*box[2] + x0 - box[0] + k
+					q := math.NaN()
+					if quality != nil {
+						q = quality[j*sbox[2]+k]
+					}
+					acc.add(idx, values[j*sbox[2]+k], q)
+				}
+			}
+		}
+		if err := dst.WriteBlock(0, 0, box, acc.result()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// MosaicFiles mosaics image files, e.g. the value datasets listed by ts.ListAll, into a new
+// file created with New over their union extent. The file is discarded if mosaicking fails.
+func MosaicFiles(fileName string, driver Driver, srcFileNames []string, copts *CreateOptions, opts *MosaicOptions) error {
+	var srcs []Reader
+	defer func() {
+		for _, src := range srcs {
+			src.Close()
+		}
+	}()
+	var params []*modis.ImageParams
+	for _, srcFileName := range srcFileNames {
+		src, err := Open(srcFileName)
+		if err != nil {
+			return err
+		}
+		srcs = append(srcs, src)
+		params = append(params, src.ImageParams())
+	}
+	p, err := MosaicParams(params...)
+	if err != nil {
+		return err
+	}
+	dst, err := New(fileName, driver, p, copts)
+	if err != nil {
+		return err
+	}
+	if err = Mosaic(dst, srcs, opts); err != nil {
+		_ = Discard(dst)
+		return err
+	}
+	return dst.Close()
+}
+
+// gridOffset returns the pixel offset of p on the grid of ref or an error if p is not on it.
+func gridOffset(ref, p *modis.ImageParams) (int, int, error) {
+	ra, pa := ref.Transform(), p.Transform()
+	if !sameProjection(ref.Projection(), p.Projection()) {
+		return 0, 0, fmt.Errorf("images in different projections")
+	}
+	if ra[2] != 0 || ra[4] != 0 || pa[2] != 0 || pa[4] != 0 {
+		return 0, 0, fmt.Errorf("rotated grids not supported")
+	}
+	if math.Abs(ra[1]-pa[1]) > gridTolerance*math.Abs(ra[1]) || math.Abs(ra[5]-pa[5]) > gridTolerance*math.Abs(ra[5]) {
+		return 0, 0, fmt.Errorf("pixel sizes differ: %vx%v vs. %vx%v", ra[1], ra[5], pa[1], pa[5])
+	}
+	fx, fy := (pa[0]-ra[0])/ra[1], (pa[3]-ra[3])/ra[5]
+	ox, oy := math.Round(fx), math.Round(fy)
+	if math.Abs(fx-ox) > gridTolerance || math.Abs(fy-oy) > gridTolerance {
+		return 0, 0, fmt.Errorf("image offset %vx%v is not a whole number of pixels", fx, fy)
+	}
+	return int(ox), int(oy), nil
+}
+
+type mosaicAccumulator struct {
+	policy  MosaicPolicy
+	values  []float64
+	counts  []int
+	quality []float64
+}
+
+func newMosaicAccumulator(n int, policy MosaicPolicy) *mosaicAccumulator {
+	res := &mosaicAccumulator{policy: policy, values: make([]float64, n)}
+	for i := range res.values {
+		res.values[i] = math.NaN()
+	}
+	switch policy {
+	case MosaicMean:
+		res.counts = make([]int, n)
+	case MosaicBestQuality:
+		res.quality = make([]float64, n)
+		copy(res.quality, res.values)
+	}
+	return res
+}
+
+func (acc *mosaicAccumulator) add(i int, v, q float64) {
+	if math.IsNaN(v) {
+		return
+	}
+	cur := acc.values[i]
+	switch acc.policy {
+	case MosaicFirst:
+		if math.IsNaN(cur) {
+			acc.values[i] = v
+		}
+	case MosaicLast:
+		acc.values[i] = v
+	case MosaicMean:
+		if math.IsNaN(cur) {
+			cur = 0
+		}
+		acc.values[i] = cur + v
+		acc.counts[i]++
+	case MosaicMax:
+		if math.IsNaN(cur) || v > cur {
+			acc.values[i] = v
+		}
+	case MosaicMin:
+		if math.IsNaN(cur) || v < cur {
+			acc.values[i] = v
+		}
+	case MosaicBestQuality:
+		if !math.IsNaN(q) && (math.IsNaN(acc.quality[i]) || q < acc.quality[i]) {
+			acc.values[i] = v
+			acc.quality[i] = q
+		}
+	}
+}
+
+func (acc *mosaicAccumulator) result() []float64 {
+	if acc.policy == MosaicMean {
+		for i, n := range acc.counts {
+			if n > 0 {
+				acc.values[i] /= float64(n)
+			}
+		}
+	}
+	return acc.values
+}
@@ -0,0 +1,136 @@
+package dataset_test
+
+import (
+	"math"
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func tile(x0, y0 float64) *modis.ImageParams {
+	return modis.ImageParamsBuilder(4, 3).Transform(modis.AffineTransform{x0, 10, 0, y0, 0, -10}).Build()
+}
+
+func filled(t *testing.T, p *modis.ImageParams, value float64) dataset.Reader {
+	r := dataset.NewInMemory(p)
+	for y := 0; y < p.YSize(); y++ {
+		for x := 0; x < p.XSize(); x++ {
+			if err := r.Write(x, y, value); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	return r
+}
+
+func TestMosaicParams(t *testing.T) {
+	a := tile(0, 100)
+	b := tile(40, 70)
+	p, err := dataset.MosaicParams(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.XSize() != 8 || p.YSize() != 6 || p.Transform() != (modis.AffineTransform{0, 10, 0, 100, 0, -10}) {
+		t.Errorf("unexpected params %dx%d %v", p.XSize(), p.YSize(), p.Transform())
+	}
+	if _, err = dataset.MosaicParams(a, tile(5, 100)); err == nil {
+		t.Error("expected error for grid offset")
+	}
+	if _, err = dataset.MosaicParams(a, a.ToBuilder().Transform(modis.AffineTransform{0, 20, 0, 100, 0, -20}).Build()); err == nil {
+		t.Error("expected error for pixel size")
+	}
+	if _, err = dataset.MosaicParams(); err == nil {
+		t.Error("expected error for no images")
+	}
+}
+
+func TestMosaic(t *testing.T) {
+	// a and b overlap by two columns, c is diagonal to a leaving a gap (missing tile)
+	pa, pb, pc := tile(0, 100), tile(20, 100), tile(40, 70)
+	a, b, c := filled(t, pa, 1), filled(t, pb, 3), filled(t, pc, 5)
+	_ = a.(dataset.Writer).Write(3, 0, math.NaN())
+	qa, qb, qc := filled(t, pa, 2), filled(t, pb, 1), filled(t, pc, 0)
+	_ = qa.(dataset.Writer).Write(2, 1, 0)
+	p, err := dataset.MosaicParams(pa, pb, pc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		policy dataset.MosaicPolicy
+		// expected values at (2,0), (3,0), (2,1) and (0,0)
+		expected [4]float64
+	}{
+		{dataset.MosaicFirst, [4]float64{1, 3, 1, 1}},
+		{dataset.MosaicLast, [4]float64{3, 3, 3, 1}},
+		{dataset.MosaicMean, [4]float64{2, 3, 2, 1}},
+		{dataset.MosaicMax, [4]float64{3, 3, 3, 1}},
+		{dataset.MosaicMin, [4]float64{1, 3, 1, 1}},
+		{dataset.MosaicBestQuality, [4]float64{3, 3, 1, 1}},
+	}
+	for _, c2 := range cases {
+		dst := dataset.NewInMemory(p)
+		opts := &dataset.MosaicOptions{Policy: c2.policy, Quality: []dataset.Reader{qa, qb, qc}}
+		if err = dataset.Mosaic(dst, []dataset.Reader{a, b, c}, opts); err != nil {
+			t.Fatal(err)
+		}
+		for i, xy := range [][2]int{{2, 0}, {3, 0}, {2, 1}, {0, 0}} {
+			if v, _ := dst.Read(xy[0], xy[1]); v != c2.expected[i] {
+				t.Errorf("policy %d: expected %v at %v, found %v", c2.policy, c2.expected[i], xy, v)
+			}
+		}
+		if v, _ := dst.Read(5, 5); v != 5 {
+			t.Errorf("policy %d: expected 5, found %v", c2.policy, v)
+		}
+		if v, _ := dst.Read(0, 4); !math.IsNaN(v) {
+			t.Errorf("policy %d: expected NaN for missing tile, found %v", c2.policy, v)
+		}
+	}
+	// the missing tile is NaN, which an integer file without NoData cannot represent
+	w, err := dataset.New(path.Join(t.TempDir(), "int.tif"), dataset.GTiff, p.ToBuilder().DataType(gdal.Int32).Build(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err = dataset.Mosaic(w, []dataset.Reader{a, b, c}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dataset.Overflows(w) == 0 {
+		t.Error("expected NaN of the missing tile to be counted")
+	}
+	if err = dataset.Mosaic(dataset.NewInMemory(p), []dataset.Reader{a, b}, &dataset.MosaicOptions{Policy: dataset.MosaicBestQuality}); err == nil {
+		t.Error("expected error for missing quality layers")
+	}
+}
+
+func TestMosaicFiles(t *testing.T) {
+	dir := t.TempDir()
+	var fileNames []string
+	for i, p := range []*modis.ImageParams{tile(0, 100), tile(40, 100)} {
+		fileName := path.Join(dir, string(rune('a'+i))+".tif")
+		w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		fillSequence(t, w)
+		w.Close()
+		fileNames = append(fileNames, fileName)
+	}
+	fileName := path.Join(dir, "mosaic.tif")
+	if err := dataset.MosaicFiles(fileName, dataset.GTiff, fileNames, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	if r.ImageParams().XSize() != 8 || r.ImageParams().YSize() != 3 {
+		t.Fatalf("unexpected size %dx%d", r.ImageParams().XSize(), r.ImageParams().YSize())
+	}
+	if v, _ := r.Read(5, 2); v != 9 {
+		t.Errorf("expected 9, found %v", v)
+	}
+}
@@ -0,0 +1,197 @@
+package dataset
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nordicsense/gdal"
+)
+
+type Compression string
+
+const (
+	NoCompression Compression = ""
+	Deflate       Compression = "DEFLATE"
+	LZW           Compression = "LZW"
+	ZSTD          Compression = "ZSTD"
+)
+
+type BigTIFF string
+
+const (
+	BigTIFFDefault  BigTIFF = ""
+	BigTIFFYes      BigTIFF = "YES"
+	BigTIFFNo       BigTIFF = "NO"
+	BigTIFFIfNeeded BigTIFF = "IF_NEEDED"
+	BigTIFFIfSafer  BigTIFF = "IF_SAFER"
+)
+
+// Predictor values as understood by GDAL.
+const (
+	PredictorNone          = 1
+	PredictorHorizontal    = 2
+	PredictorFloatingPoint = 3
+)
+
+// CreateOptions define how a new file is laid out by the driver. The zero value (or nil)
+// corresponds to driver defaults, for GTiff an uncompressed striped file.
+type CreateOptions struct {
+	Compression Compression
+	// Predictor for DEFLATE, LZW and ZSTD compression, 0 for driver default.
+	Predictor int
+	// Tiled requests internal tiling with BlockXSize x BlockYSize tiles (0 for driver default).
+	Tiled      bool
+	BlockXSize int
+	BlockYSize int
+	BigTIFF    BigTIFF
+	// Extra creation options passed to the driver as they are.
+	Extra map[string]string
+	// Atomic writes into a temporary file in the same directory that is renamed to the target
+	// file name only if Close succeeds and removed otherwise. Only drivers writing a single
+	// file support atomic mode.
+	Atomic bool
+}
+
+type driverCapabilities struct {
+	compressions []Compression
+	predictor    bool
+	tiling       bool
+	bigTIFF      bool
+	// createCopy drivers cannot create files for writing, see newCreateCopy.
+	createCopy bool
+	// atomic drivers write a single file that can be renamed into place.
+	atomic bool
+}
+
+var capabilities = map[Driver]driverCapabilities{
+	GTiff:  {compressions: []Compression{Deflate, LZW, ZSTD}, predictor: true, tiling: true, bigTIFF: true, atomic: true},
+	COG:    {compressions: []Compression{Deflate, LZW, ZSTD}, predictor: true, bigTIFF: true, createCopy: true, atomic: true},
+	NetCDF: {compressions: []Compression{Deflate}, atomic: true},
+	ENVI:   {},
+	MEM:    {},
+	VRT:    {createCopy: true, atomic: true},
+}
+
+// Validate checks that the options are supported by the driver for images of given data type.
+func (o *CreateOptions) Validate(driver Driver, dt gdal.DataType) error {
+	if o == nil {
+		return nil
+	}
+	caps := capabilities[driver]
+	if o.Compression != NoCompression {
+		supported := false
+		for _, c := range caps.compressions {
+			supported = supported || c == o.Compression
+		}
+		if !supported {
+			return fmt.Errorf("compression %s is not supported by driver %s", o.Compression, driver)
+		}
+	}
+	if o.Predictor != 0 {
+		if !caps.predictor {
+			return fmt.Errorf("predictor is not supported by driver %s", driver)
+		}
+		if o.Predictor < PredictorNone || o.Predictor > PredictorFloatingPoint {
+			return fmt.Errorf("unknown predictor %d", o.Predictor)
+		}
+		if o.Compression == NoCompression {
+			return fmt.Errorf("predictor requires compression")
+		}
+		if o.Predictor == PredictorFloatingPoint && !isFloat(dt) {
+			return fmt.Errorf("floating point predictor is not supported for data type %s", dt.Name())
+		}
+	}
+	if o.Tiled && !caps.tiling {
+		return fmt.Errorf("tiling is not supported by driver %s", driver)
+	}
+	if o.BlockXSize != 0 || o.BlockYSize != 0 {
+		if !o.Tiled {
+			return fmt.Errorf("block size requires tiling")
+		}
+		if o.BlockXSize < 0 || o.BlockXSize%16 != 0 || o.BlockYSize < 0 || o.BlockYSize%16 != 0 {
+			return fmt.Errorf("block size %dx%d must be a multiple of 16", o.BlockXSize, o.BlockYSize)
+		}
+	}
+	if o.Atomic && !caps.atomic {
+		return fmt.Errorf("atomic mode is not supported by driver %s", driver)
+	}
+	if o.BigTIFF != BigTIFFDefault {
+		if !caps.bigTIFF {
+			return fmt.Errorf("BIGTIFF is not supported by driver %s", driver)
+		}
+		switch o.BigTIFF {
+		case BigTIFFYes, BigTIFFNo, BigTIFFIfNeeded, BigTIFFIfSafer:
+		default:
+			return fmt.Errorf("unknown BIGTIFF value %s", o.BigTIFF)
+		}
+	}
+	structured := o.structured()
+	for k := range o.Extra {
+		if _, ok := structured[strings.ToUpper(k)]; ok {
+			return fmt.Errorf("extra option %s conflicts with a structured option", k)
+		}
+	}
+	return nil
+}
+
+// validateExtra checks the extra options against the creation option list published by the driver
+// (an empty list is not checked).
+func (o *CreateOptions) validateExtra(driver Driver, optionList string) error {
+	if o == nil || len(o.Extra) == 0 || optionList == "" {
+		return nil
+	}
+	known := make(map[string]bool)
+	for _, m := range optionNamePattern.FindAllStringSubmatch(optionList, -1) {
+		known[strings.ToUpper(m[1])] = true
+	}
+	for k := range o.Extra {
+		if !known[strings.ToUpper(k)] {
+			return fmt.Errorf("creation option %s is not supported by driver %s", k, driver)
+		}
+	}
+	return nil
+}
+
+var optionNamePattern = regexp.MustCompile(`<Option\s+name=['"]([^'"]+)['"]`)
+
+func (o *CreateOptions) structured() map[string]string {
+	res := make(map[string]string)
+	if o.Compression != NoCompression {
+		res["COMPRESS"] = string(o.Compression)
+	}
+	if o.Predictor != 0 {
+		res["PREDICTOR"] = strconv.Itoa(o.Predictor)
+	}
+	if o.Tiled {
+		res["TILED"] = "YES"
+	}
+	if o.BlockXSize != 0 {
+		res["BLOCKXSIZE"] = strconv.Itoa(o.BlockXSize)
+	}
+	if o.BlockYSize != 0 {
+		res["BLOCKYSIZE"] = strconv.Itoa(o.BlockYSize)
+	}
+	if o.BigTIFF != BigTIFFDefault {
+		res["BIGTIFF"] = string(o.BigTIFF)
+	}
+	return res
+}
+
+// toGDAL converts options into the KEY=VALUE list expected by GDAL (nil for no options).
+func (o *CreateOptions) toGDAL() []string {
+	if o == nil {
+		return nil
+	}
+	var res []string
+	for k, v := range o.structured() {
+		res = append(res, k+"="+v)
+	}
+	for k, v := range o.Extra {
+		res = append(res, strings.ToUpper(k)+"="+v)
+	}
+	sort.Strings(res)
+	return res
+}
@@ -0,0 +1,61 @@
+package dataset_test
+
+import (
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestCreateOptions_Validate(t *testing.T) {
+	cases := []struct {
+		name  string
+		opts  *dataset.CreateOptions
+		dt    gdal.DataType
+		valid bool
+	}{
+		{name: "nil", opts: nil, valid: true},
+		{name: "zero", opts: &dataset.CreateOptions{}, valid: true},
+		{name: "deflate", opts: &dataset.CreateOptions{Compression: dataset.Deflate, Predictor: dataset.PredictorHorizontal}, valid: true},
+		{name: "tiled", opts: &dataset.CreateOptions{Tiled: true, BlockXSize: 512, BlockYSize: 256, BigTIFF: dataset.BigTIFFIfSafer}, valid: true},
+		{name: "extra", opts: &dataset.CreateOptions{Compression: dataset.ZSTD, Extra: map[string]string{"ZSTD_LEVEL": "9"}}, valid: true},
+		{name: "atomic", opts: &dataset.CreateOptions{Atomic: true}, valid: true},
+		{name: "unknown compression", opts: &dataset.CreateOptions{Compression: "JPEG2000"}},
+		{name: "predictor without compression", opts: &dataset.CreateOptions{Predictor: dataset.PredictorHorizontal}},
+		{name: "floating point predictor", opts: &dataset.CreateOptions{Compression: dataset.LZW, Predictor: dataset.PredictorFloatingPoint}, dt: gdal.Float32, valid: true},
+		{name: "floating point predictor for integers", opts: &dataset.CreateOptions{Compression: dataset.LZW, Predictor: dataset.PredictorFloatingPoint}, dt: gdal.Int16},
+		{name: "unknown predictor", opts: &dataset.CreateOptions{Compression: dataset.LZW, Predictor: 7}},
+		{name: "block size without tiling", opts: &dataset.CreateOptions{BlockXSize: 256, BlockYSize: 256}},
+		{name: "odd block size", opts: &dataset.CreateOptions{Tiled: true, BlockXSize: 100, BlockYSize: 256}},
+		{name: "unknown bigtiff", opts: &dataset.CreateOptions{BigTIFF: "MAYBE"}},
+		{name: "conflicting extra", opts: &dataset.CreateOptions{Compression: dataset.LZW, Extra: map[string]string{"compress": "DEFLATE"}}},
+	}
+	for _, c := range cases {
+		err := c.opts.Validate(dataset.GTiff, c.dt)
+		if c.valid && err != nil {
+			t.Errorf("%s: unexpected error %v", c.name, err)
+		} else if !c.valid && err == nil {
+			t.Errorf("%s: expected error", c.name)
+		}
+	}
+}
+
+func TestNew_CreateOptions(t *testing.T) {
+	p := modis.ImageParamsBuilder(64, 64).DataType(gdal.Int16).Build()
+	dir := t.TempDir()
+	opts := &dataset.CreateOptions{Compression: dataset.Deflate, Predictor: dataset.PredictorHorizontal, Tiled: true,
+		BlockXSize: 32, BlockYSize: 32, BigTIFF: dataset.BigTIFFIfNeeded}
+	w, err := dataset.New(path.Join(dir, "compressed.tif"), dataset.GTiff, p, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Write(10, 10, 42); err != nil {
+		t.Error(err)
+	}
+	w.Close()
+	if _, err = dataset.New(path.Join(dir, "invalid.tif"), dataset.GTiff, p, &dataset.CreateOptions{Compression: "RLE"}); err == nil {
+		t.Error("expected error for unsupported compression")
+	}
+}
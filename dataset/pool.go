@@ -0,0 +1,145 @@
+package dataset
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nordicsense/modis"
+)
+
+// OpenConcurrent opens a file with a pool of independently opened GDAL handles. Unlike the
+// readers returned by Open, the returned Reader is safe for concurrent use: every call borrows
+// a handle from the pool for its duration, so that up to handles calls run in parallel and
+// further calls wait for a handle to be returned.
+func OpenConcurrent(fileName string, handles int) (Reader, error) {
+	if handles < 1 {
+		return nil, fmt.Errorf("at least one handle required, found %d", handles)
+	}
+	res := &pooledFile{handles: make(chan *imageFile, handles), closed: make(chan struct{})}
+	for i := 0; i < handles; i++ {
+		r, err := Open(fileName)
+		if err != nil {
+			res.Close()
+			return nil, err
+		}
+		res.handles <- r.(*imageFile)
+		res.size++
+	}
+	h := <-res.handles
+	res.p = h.ImageParams()
+	res.bx, res.by = h.BlockSize()
+	res.handles <- h
+	return res, nil
+}
+
+// errClosed is returned by calls to a pooled reader after Close.
+var errClosed = errors.New("dataset closed")
+
+type pooledFile struct {
+	p       *modis.ImageParams
+	bx, by  int
+	handles chan *imageFile
+	size    int
+	// closed is closed by Close, calls waiting for a handle then fail.
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+// with runs fn with a handle borrowed from the pool.
+func (ds *pooledFile) with(fn func(h *imageFile) error) error {
+	select {
+	case <-ds.closed:
+		return errClosed
+	default:
+	}
+	select {
+	case h := <-ds.handles:
+		defer func() { ds.handles <- h }()
+		return fn(h)
+	case <-ds.closed:
+		return errClosed
+	}
+}
+
+func (ds *pooledFile) ImageParams() *modis.ImageParams {
+	return ds.p
+}
+
+func (ds *pooledFile) Read(x, y int) (res float64, err error) {
+	err = ds.with(func(h *imageFile) error {
+		res, err = h.Read(x, y)
+		return err
+	})
+	return res, err
+}
+
+func (ds *pooledFile) ReadAtLatLon(ll modis.LatLon) (res float64, err error) {
+	err = ds.with(func(h *imageFile) error {
+		res, err = h.ReadAtLatLon(ll)
+		return err
+	})
+	return res, err
+}
+
+func (ds *pooledFile) ReadTime(x, y int) (res time.Time, err error) {
+	err = ds.with(func(h *imageFile) error {
+		res, err = h.ReadTime(x, y)
+		return err
+	})
+	return res, err
+}
+
+func (ds *pooledFile) ReadTimeAtLatLon(ll modis.LatLon) (res time.Time, err error) {
+	err = ds.with(func(h *imageFile) error {
+		res, err = h.ReadTimeAtLatLon(ll)
+		return err
+	})
+	return res, err
+}
+
+func (ds *pooledFile) ReadBlock(x, y int, box modis.Box) (res []float64, err error) {
+	err = ds.with(func(h *imageFile) error {
+		res, err = h.ReadBlock(x, y, box)
+		return err
+	})
+	return res, err
+}
+
+func (ds *pooledFile) ReadRawBlock(x, y int, box modis.Box) (res *RawBuffer, err error) {
+	err = ds.with(func(h *imageFile) error {
+		res, err = h.ReadRawBlock(x, y, box)
+		return err
+	})
+	return res, err
+}
+
+func (ds *pooledFile) BlockSize() (int, int) {
+	return ds.bx, ds.by
+}
+
+func (ds *pooledFile) Blocks(halo int) *BlockIterator {
+	return newBlockIterator(ds, halo)
+}
+
+func (ds *pooledFile) ToMemory() (res *inMemory) {
+	_ = ds.with(func(h *imageFile) error {
+		res = h.ToMemory()
+		return nil
+	})
+	return res
+}
+
+// Close waits for all handles to be returned to the pool and closes them, returning the first
+// error. Calls after Close fail.
+func (ds *pooledFile) Close() error {
+	ds.closeOnce.Do(func() { close(ds.closed) })
+	var err error
+	for ; ds.size > 0; ds.size-- {
+		if e := (<-ds.handles).Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
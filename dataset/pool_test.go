@@ -0,0 +1,67 @@
+package dataset_test
+
+import (
+	"path"
+	"sync"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+// Run with -race to verify that readers from OpenConcurrent are safe for concurrent use.
+func TestOpenConcurrent(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "concurrent.tif")
+	p := modis.ImageParamsBuilder(50, 40).DataType(gdal.Int32).Build()
+	w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fillSequence(t, w)
+	w.Close()
+
+	r, err := dataset.OpenConcurrent(fileName, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for y := g; y < 40; y += 8 {
+				for x := 0; x < 50; x++ {
+					v, err := r.Read(x, y)
+					if err != nil {
+						errs <- err
+						return
+					}
+					if v != float64(y*50+x) {
+						t.Errorf("expected %d at (%d,%d), found %v", y*50+x, x, y, v)
+					}
+				}
+				if _, err := r.ReadBlock(0, y, modis.Box{0, 0, 50, 1}); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+	if err = r.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = r.Read(0, 0); err == nil {
+		t.Error("expected error reading after Close")
+	}
+	if _, err = dataset.OpenConcurrent(fileName, 0); err == nil {
+		t.Error("expected error for empty pool")
+	}
+}
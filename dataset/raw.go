@@ -0,0 +1,309 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+)
+
+// RawBuffer holds raster values in the native data type of a band, i.e. without scale,
+// offset or NaN substitution applied. Use it for QA bitfields, classes and other layers
+// that must be handled as integers.
+type RawBuffer struct {
+	dt   gdal.DataType
+	data interface{}
+}
+
+// NewRawBuffer allocates a raw buffer of given data type and number of values.
+func NewRawBuffer(dt gdal.DataType, size int) (*RawBuffer, error) {
+	var data interface{}
+	switch dt {
+	case gdal.Byte:
+		data = make([]uint8, size)
+	case gdal.Int16:
+		data = make([]int16, size)
+	case gdal.UInt16:
+		data = make([]uint16, size)
+	case gdal.Int32:
+		data = make([]int32, size)
+	case gdal.UInt32:
+		data = make([]uint32, size)
+	case gdal.Float32:
+		data = make([]float32, size)
+	case gdal.Float64:
+		data = make([]float64, size)
+	default:
+		return nil, fmt.Errorf("unsupported raw data type %s", dt.Name())
+	}
+	return &RawBuffer{dt: dt, data: data}, nil
+}
+
+func (b *RawBuffer) DataType() gdal.DataType {
+	return b.dt
+}
+
+// Data returns the underlying typed slice, e.g. []uint16 for gdal.UInt16.
+func (b *RawBuffer) Data() interface{} {
+	return b.data
+}
+
+func (b *RawBuffer) Len() int {
+	switch data := b.data.(type) {
+	case []uint8:
+		return len(data)
+	case []int16:
+		return len(data)
+	case []uint16:
+		return len(data)
+	case []int32:
+		return len(data)
+	case []uint32:
+		return len(data)
+	case []float32:
+		return len(data)
+	case []float64:
+		return len(data)
+	}
+	return 0
+}
+
+// At returns the raw value at index i converted to float64.
+func (b *RawBuffer) At(i int) float64 {
+	switch data := b.data.(type) {
+	case []uint8:
+		return float64(data[i])
+	case []int16:
+		return float64(data[i])
+	case []uint16:
+		return float64(data[i])
+	case []int32:
+		return float64(data[i])
+	case []uint32:
+		return float64(data[i])
+	case []float32:
+		return float64(data[i])
+	case []float64:
+		return data[i]
+	}
+	return math.NaN()
+}
+
+// IntAt returns the raw value at index i converted to int64 (floating point values are truncated).
+func (b *RawBuffer) IntAt(i int) int64 {
+	switch data := b.data.(type) {
+	case []uint8:
+		return int64(data[i])
+	case []int16:
+		return int64(data[i])
+	case []uint16:
+		return int64(data[i])
+	case []int32:
+		return int64(data[i])
+	case []uint32:
+		return int64(data[i])
+	case []float32:
+		return int64(data[i])
+	case []float64:
+		return int64(data[i])
+	}
+	return 0
+}
+
+// Set stores v at index i converting it to the native type of the buffer.
+func (b *RawBuffer) Set(i int, v float64) {
+	switch data := b.data.(type) {
+	case []uint8:
+		data[i] = uint8(v)
+	case []int16:
+		data[i] = int16(v)
+	case []uint16:
+		data[i] = uint16(v)
+	case []int32:
+		data[i] = int32(v)
+	case []uint32:
+		data[i] = uint32(v)
+	case []float32:
+		data[i] = float32(v)
+	case []float64:
+		data[i] = v
+	}
+}
+
+func (b *RawBuffer) Bytes() []uint8 {
+	res, _ := b.data.([]uint8)
+	return res
+}
+
+func (b *RawBuffer) Int16s() []int16 {
+	res, _ := b.data.([]int16)
+	return res
+}
+
+func (b *RawBuffer) Uint16s() []uint16 {
+	res, _ := b.data.([]uint16)
+	return res
+}
+
+func (b *RawBuffer) Int32s() []int32 {
+	res, _ := b.data.([]int32)
+	return res
+}
+
+func (b *RawBuffer) Uint32s() []uint32 {
+	res, _ := b.data.([]uint32)
+	return res
+}
+
+func (b *RawBuffer) Float32s() []float32 {
+	res, _ := b.data.([]float32)
+	return res
+}
+
+func (b *RawBuffer) Float64s() []float64 {
+	res, _ := b.data.([]float64)
+	return res
+}
+
+// OverflowError reports values that could not be represented in the data type of an image.
+type OverflowError struct {
+	Count    int
+	DataType gdal.DataType
+}
+
+func (e *OverflowError) Error() string {
+	return fmt.Sprintf("%d value(s) not representable as %s", e.Count, e.DataType.Name())
+}
+
+// rawFromValues converts scaled values into raw values of the image data type: NaN is
+// mapped to the NoData value (if declared), scale and offset are reverted, integer values
+// are rounded and all finite values clamped to the range of the type. The number of values
+// that could not be represented (clamped, NaN without NoData written as 0, or valid values
+// stored as the NoData value) is returned along with the buffer.
+func rawFromValues(p *modis.ImageParams, values []float64) (*RawBuffer, int, error) {
+	if err := checkNoData(p); err != nil {
+		return nil, 0, err
+	}
+	res, err := NewRawBuffer(p.DataType(), len(values))
+	if err != nil {
+		return nil, 0, err
+	}
+	lo, hi := typeRange(p.DataType())
+	nan, hasnan := p.NaN()
+	overflows := 0
+	for i, v := range values {
+		if math.IsNaN(v) {
+			if hasnan {
+				res.Set(i, nan)
+			} else if isFloat(p.DataType()) {
+				res.Set(i, v)
+			} else {
+				overflows++
+			}
+			continue
+		}
+		v = (v - p.Offset()) / p.Scale()
+		if !isFloat(p.DataType()) {
+			v = math.Round(v)
+		}
+		clamped := false
+		if !isFloat(p.DataType()) || !math.IsInf(v, 0) {
+			if v < lo {
+				v, clamped = lo, true
+			} else if v > hi {
+				v, clamped = hi, true
+			}
+		}
+		res.Set(i, v)
+		// a valid value stored as NoData reads back as NaN
+		if clamped || (hasnan && res.At(i) == nan) {
+			overflows++
+		}
+	}
+	return res, overflows, nil
+}
+
+// checkNoData checks that the NoData value, if any, is exactly representable in the data type,
+// NaN being valid for floating point types only.
+func checkNoData(p *modis.ImageParams) error {
+	nan, ok := p.NaN()
+	if !ok {
+		return nil
+	}
+	dt := p.DataType()
+	var valid bool
+	switch {
+	case math.IsNaN(nan):
+		valid = isFloat(dt)
+	case dt == gdal.Float32:
+		valid = float64(float32(nan)) == nan
+	case dt == gdal.Float64:
+		valid = true
+	default:
+		lo, hi := typeRange(dt)
+		valid = nan == math.Trunc(nan) && nan >= lo && nan <= hi
+	}
+	if !valid {
+		return fmt.Errorf("NoData value %v cannot be represented as %s", nan, dt.Name())
+	}
+	return nil
+}
+
+// rawFromReadValues converts values read from readers that hold no raw data, failing with an
+// *OverflowError if any could not be represented.
+func rawFromReadValues(p *modis.ImageParams, values []float64) (*RawBuffer, error) {
+	res, overflows, err := rawFromValues(p, values)
+	if err != nil {
+		return nil, err
+	}
+	if overflows > 0 {
+		return nil, &OverflowError{Count: overflows, DataType: p.DataType()}
+	}
+	return res, nil
+}
+
+// valuesFromRaw converts raw values into scaled ones with NoData mapped to NaN.
+func valuesFromRaw(p *modis.ImageParams, raw *RawBuffer) []float64 {
+	res := make([]float64, raw.Len())
+	nan, hasnan := p.NaN()
+	for i := range res {
+		v := raw.At(i)
+		if hasnan && v == nan {
+			res[i] = math.NaN()
+		} else {
+			res[i] = v*p.Scale() + p.Offset()
+		}
+	}
+	return res
+}
+
+// typeRange returns the range of finite values representable by the data type.
+func typeRange(dt gdal.DataType) (float64, float64) {
+	switch dt {
+	case gdal.Byte:
+		return 0, math.MaxUint8
+	case gdal.Int16:
+		return math.MinInt16, math.MaxInt16
+	case gdal.UInt16:
+		return 0, math.MaxUint16
+	case gdal.Int32:
+		return math.MinInt32, math.MaxInt32
+	case gdal.UInt32:
+		return 0, math.MaxUint32
+	case gdal.Float32:
+		return -math.MaxFloat32, math.MaxFloat32
+	}
+	return math.Inf(-1), math.Inf(1)
+}
+
+func isFloat(dt gdal.DataType) bool {
+	return dt == gdal.Float32 || dt == gdal.Float64
+}
+
+func checkRawSize(box modis.Box, buffer *RawBuffer) error {
+	if buffer == nil || buffer.Len() != box[2]*box[3] {
+		return fmt.Errorf("raw buffer does not match box %v", box)
+	}
+	return nil
+}
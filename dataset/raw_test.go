@@ -0,0 +1,87 @@
+package dataset_test
+
+import (
+	"errors"
+	"math"
+	"path"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestRawBlock_InMemory(t *testing.T) {
+	p := modis.ImageParamsBuilder(3, 2).DataType(gdal.UInt16).Scale(0.02).NaN(0).Build()
+	ds := dataset.NewInMemory(p)
+	raw, _ := dataset.NewRawBuffer(gdal.UInt16, 6)
+	copy(raw.Uint16s(), []uint16{0, 14000, 14500, 15000, 15500, 16000})
+	if err := ds.WriteRawBlock(0, 0, modis.Box{0, 0, 3, 2}, raw); err != nil {
+		t.Fatal(err)
+	}
+	v, _ := ds.Read(1, 0)
+	if math.Abs(v-280.0) > 1e-9 {
+		t.Errorf("expected 280, found %v", v)
+	}
+	if v, _ = ds.Read(0, 0); !math.IsNaN(v) {
+		t.Errorf("expected NaN, found %v", v)
+	}
+	res, err := ds.ReadRawBlock(0, 0, modis.Box{0, 0, 3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DataType() != gdal.UInt16 {
+		t.Errorf("expected UInt16, found %s", res.DataType().Name())
+	}
+	for i, expected := range raw.Uint16s() {
+		if res.Uint16s()[i] != expected {
+			t.Errorf("expected %d at %d, found %d", expected, i, res.Uint16s()[i])
+		}
+	}
+}
+
+func TestRawBlock_InMemoryOverflow(t *testing.T) {
+	ds := dataset.NewInMemory(modis.ImageParamsBuilder(2, 1).DataType(gdal.Int32).Build())
+	if err := ds.Write(0, 0, 7); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ds.ReadRawBlock(0, 0, modis.Box{0, 0, 2, 1})
+	var overflow *dataset.OverflowError
+	if !errors.As(err, &overflow) || overflow.Count != 1 {
+		t.Errorf("expected NaN without NoData to be reported, found %v", err)
+	}
+	if _, err = dataset.Map(ds, func(v float64) float64 { return v }).ReadRawBlock(0, 0, modis.Box{0, 0, 1, 1}); err != nil {
+		t.Errorf("unexpected error %v", err)
+	}
+}
+
+func TestRawBlock_File(t *testing.T) {
+	p := modis.ImageParamsBuilder(4, 4).DataType(gdal.Int16).Scale(0.5).Offset(10).NaN(-1).Build()
+	w, err := dataset.New(path.Join(t.TempDir(), "raw.tif"), dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	raw, _ := dataset.NewRawBuffer(gdal.Int16, 4)
+	copy(raw.Int16s(), []int16{-1, 3, 7, 11})
+	if err = w.WriteRawBlock(1, 1, modis.Box{0, 0, 2, 2}, raw); err != nil {
+		t.Fatal(err)
+	}
+	r := w.(dataset.Reader)
+	res, err := r.ReadRawBlock(1, 1, modis.Box{0, 0, 2, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range raw.Int16s() {
+		if res.Int16s()[i] != expected {
+			t.Errorf("expected %d at %d, found %d", expected, i, res.Int16s()[i])
+		}
+	}
+	values, _ := r.ReadBlock(1, 1, modis.Box{0, 0, 2, 2})
+	if !math.IsNaN(values[0]) || values[1] != 11.5 || values[3] != 15.5 {
+		t.Errorf("unexpected scaled values %v", values)
+	}
+	if err = w.WriteRawBlock(0, 0, modis.Box{0, 0, 3, 3}, raw); err == nil {
+		t.Error("expected error for mismatching buffer size")
+	}
+}
@@ -0,0 +1,207 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+	"github.com/nordicsense/modis/sketch"
+)
+
+// StatsOptions define what Stats computes in addition to count, min, max, mean and std.
+type StatsOptions struct {
+	// Percentiles in [0,100], approximated by a streaming sketch.
+	Percentiles []float64
+	// Compression of the sketch, see sketch.New.
+	Compression float64
+	// Bins of a histogram with equal width over [HistMin, HistMax]; the range of the data is
+	// used (requiring a second pass over the image) if the two are equal.
+	Bins             int
+	HistMin, HistMax float64
+	// Mask restricts statistics to pixels where the aligned mask is neither NaN nor 0.
+	Mask Reader
+}
+
+// Histogram counts values in equal width bins over [Min, Max], values outside are not counted.
+type Histogram struct {
+	Min, Max float64
+	Counts   []int
+}
+
+// Statistics summarise the values of an image, statistics of images without valid values are NaN.
+type Statistics struct {
+	// Count is the number of pixels considered (within the mask), NaNCount those of them with NaN.
+	Count    int
+	NaNCount int
+	Min      float64
+	Max      float64
+	Mean     float64
+	Std      float64
+	// Percentiles in the order of the levels of the options.
+	Percentiles []float64
+	Histogram   *Histogram
+}
+
+// BandStatistics is implemented by file datasets that store statistics in GDAL band metadata
+// (STATISTICS_MINIMUM, STATISTICS_MAXIMUM, STATISTICS_MEAN, STATISTICS_STDDEV and
+// STATISTICS_VALID_PERCENT) in raw, unscaled values like GDAL itself. Percentiles and
+// histograms are not stored.
+type BandStatistics interface {
+	SetStatistics(s *Statistics) error
+	// Statistics returns the stored statistics, false if none are stored.
+	Statistics() (*Statistics, bool)
+}
+
+// Stats computes statistics of r block by block.
+func Stats(r Reader, opts *StatsOptions) (*Statistics, error) {
+	if opts == nil {
+		opts = &StatsOptions{}
+	}
+	for _, level := range opts.Percentiles {
+		if level < 0 || level > 100 {
+			return nil, fmt.Errorf("percentile %v outside of [0,100]", level)
+		}
+	}
+	if opts.Bins < 0 || opts.HistMin > opts.HistMax {
+		return nil, fmt.Errorf("invalid histogram of %d bins over [%v,%v]", opts.Bins, opts.HistMin, opts.HistMax)
+	}
+	if opts.Mask != nil {
+		if err := CheckAligned(r.ImageParams(), opts.Mask.ImageParams()); err != nil {
+			return nil, err
+		}
+	}
+	res := &Statistics{Min: math.NaN(), Max: math.NaN(), Mean: math.NaN(), Std: math.NaN()}
+	var hist *Histogram
+	if opts.Bins > 0 && opts.HistMin < opts.HistMax {
+		hist = &Histogram{Min: opts.HistMin, Max: opts.HistMax, Counts: make([]int, opts.Bins)}
+	}
+	sk := sketch.New(opts.Compression)
+	mean, m2, valid := 0.0, 0.0, 0
+	err := eachValue(r, opts.Mask, func(v float64) {
+		res.Count++
+		if math.IsNaN(v) {
+			res.NaNCount++
+			return
+		}
+		valid++
+		delta := v - mean
+		mean += delta / float64(valid)
+		m2 += delta * (v - mean)
+		sk.Add(v, 1)
+		hist.add(v)
+	})
+	if err != nil {
+		return nil, err
+	}
+	if valid > 0 {
+		res.Min, res.Max, res.Mean, res.Std = sk.Min(), sk.Max(), mean, math.Sqrt(m2/float64(valid))
+	}
+	for _, level := range opts.Percentiles {
+		res.Percentiles = append(res.Percentiles, sk.Quantile(level/100))
+	}
+	if opts.Bins > 0 && hist == nil {
+		hist = &Histogram{Min: res.Min, Max: res.Max, Counts: make([]int, opts.Bins)}
+		if valid > 0 {
+			if err = eachValue(r, opts.Mask, hist.add); err != nil {
+				return nil, err
+			}
+		}
+	}
+	res.Histogram = hist
+	return res, nil
+}
+
+// eachValue calls fn for every pixel of r within the mask.
+func eachValue(r Reader, mask Reader, fn func(v float64)) error {
+	it := r.Blocks(0)
+	for it.Next() {
+		var m []float64
+		if mask != nil {
+			var err error
+			if m, err = mask.ReadBlock(0, 0, it.Box()); err != nil {
+				return err
+			}
+		}
+		for i, v := range it.Data() {
+			if m == nil || (!math.IsNaN(m[i]) && m[i] != 0) {
+				fn(v)
+			}
+		}
+	}
+	return it.Err()
+}
+
+func (h *Histogram) add(v float64) {
+	if h == nil || math.IsNaN(v) || v < h.Min || v > h.Max {
+		return
+	}
+	n := len(h.Counts)
+	i := n - 1
+	if h.Max > h.Min {
+		i = num.MinInt(int((v-h.Min)/(h.Max-h.Min)*float64(n)), n-1)
+	}
+	h.Counts[i]++
+}
+
+const (
+	statsMinimum      = "STATISTICS_MINIMUM"
+	statsMaximum      = "STATISTICS_MAXIMUM"
+	statsMean         = "STATISTICS_MEAN"
+	statsStdDev       = "STATISTICS_STDDEV"
+	statsValidPercent = "STATISTICS_VALID_PERCENT"
+)
+
+func (ds *imageFile) SetStatistics(s *Statistics) error {
+	p := ds.ImageParams()
+	if s.Count > s.NaNCount {
+		min, max := rawValue(p, s.Min), rawValue(p, s.Max)
+		if p.Scale() < 0 {
+			min, max = max, min
+		}
+		if err := ds.RasterBand(band).SetStatistics(min, max, rawValue(p, s.Mean), s.Std/math.Abs(p.Scale())); err != nil {
+			return err
+		}
+	}
+	validPercent := 0.0
+	if s.Count > 0 {
+		validPercent = 100 * float64(s.Count-s.NaNCount) / float64(s.Count)
+	}
+	rb := ds.RasterBand(band)
+	return rb.SetMetadataItem(statsValidPercent, strconv.FormatFloat(validPercent, 'g', -1, 64), "")
+}
+
+func (ds *imageFile) Statistics() (*Statistics, bool) {
+	rb := ds.RasterBand(band)
+	validPercent, err := strconv.ParseFloat(rb.MetadataItem(statsValidPercent, ""), 64)
+	if err != nil {
+		return nil, false
+	}
+	p := ds.ImageParams()
+	res := &Statistics{Count: p.XSize() * p.YSize(), Min: math.NaN(), Max: math.NaN(), Mean: math.NaN(), Std: math.NaN()}
+	res.NaNCount = int(math.Round(float64(res.Count) * (1 - validPercent/100)))
+	if res.NaNCount == res.Count {
+		return res, true
+	}
+	var values []float64
+	for _, key := range []string{statsMinimum, statsMaximum, statsMean, statsStdDev} {
+		v, err := strconv.ParseFloat(rb.MetadataItem(key, ""), 64)
+		if err != nil {
+			return nil, false
+		}
+		values = append(values, v)
+	}
+	res.Min, res.Max = values[0]*p.Scale()+p.Offset(), values[1]*p.Scale()+p.Offset()
+	if p.Scale() < 0 {
+		res.Min, res.Max = res.Max, res.Min
+	}
+	res.Mean = values[2]*p.Scale() + p.Offset()
+	res.Std = values[3] * math.Abs(p.Scale())
+	return res, true
+}
+
+// rawValue reverts scale and offset without rounding or clamping.
+func rawValue(p *modis.ImageParams, v float64) float64 {
+	return (v - p.Offset()) / p.Scale()
+}
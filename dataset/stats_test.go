@@ -0,0 +1,96 @@
+package dataset_test
+
+import (
+	"math"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestStats(t *testing.T) {
+	p := modis.ImageParamsBuilder(10, 100).Build()
+	r := dataset.NewInMemory(p)
+	fillSequence(t, r)
+	for x := 0; x < 10; x++ {
+		_ = r.Write(x, 99, math.NaN())
+	}
+	s, err := dataset.Stats(r, &dataset.StatsOptions{Percentiles: []float64{0, 50, 100}, Bins: 3})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// values 0..989
+	if s.Count != 1000 || s.NaNCount != 10 || s.Min != 0 || s.Max != 989 || s.Mean != 494.5 {
+		t.Errorf("unexpected statistics %+v", s)
+	}
+	if math.Abs(s.Std-math.Sqrt((990*990-1)/12.)) > 1e-9 {
+		t.Errorf("expected std %v, found %v", math.Sqrt((990*990-1)/12.), s.Std)
+	}
+	if !reflect.DeepEqual(s.Percentiles, []float64{0, 494.5, 989}) {
+		t.Errorf("unexpected percentiles %v", s.Percentiles)
+	}
+	if h := s.Histogram; h.Min != 0 || h.Max != 989 || !reflect.DeepEqual(h.Counts, []int{330, 330, 330}) {
+		t.Errorf("unexpected histogram %+v", h)
+	}
+
+	mask := dataset.NewInMemory(p)
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 10; x++ {
+			if x < 5 {
+				_ = mask.Write(x, y, 1)
+			} else {
+				_ = mask.Write(x, y, 0)
+			}
+		}
+	}
+	s, err = dataset.Stats(r, &dataset.StatsOptions{Mask: mask, Bins: 2, HistMin: 0, HistMax: 100})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Count != 500 || s.NaNCount != 5 || s.Max != 984 || !reflect.DeepEqual(s.Histogram.Counts, []int{25, 26}) {
+		t.Errorf("unexpected statistics %+v %+v", s, s.Histogram)
+	}
+	if _, err = dataset.Stats(r, &dataset.StatsOptions{Percentiles: []float64{-1}}); err == nil {
+		t.Error("expected error for percentile")
+	}
+	if _, err = dataset.Stats(r, &dataset.StatsOptions{Mask: dataset.NewInMemory(modis.ImageParamsBuilder(10, 10).Build())}); err == nil {
+		t.Error("expected alignment error")
+	}
+}
+
+func TestBandStatistics(t *testing.T) {
+	fileName := path.Join(t.TempDir(), "stats.tif")
+	w, err := dataset.New(fileName, dataset.GTiff, testParams(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writeTestData(t, w)
+	s, err := dataset.Stats(w.(dataset.Reader), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.(dataset.BandStatistics).SetStatistics(s); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	stored, ok := r.(dataset.BandStatistics).Statistics()
+	if !ok {
+		t.Fatal("expected stored statistics")
+	}
+	for _, pair := range [][2]float64{{s.Min, stored.Min}, {s.Max, stored.Max}, {s.Mean, stored.Mean}, {s.Std, stored.Std}} {
+		if math.Abs(pair[0]-pair[1]) > 1e-9 {
+			t.Errorf("expected %v, found %v", pair[0], pair[1])
+		}
+	}
+	if stored.Count != s.Count || stored.NaNCount != s.NaNCount {
+		t.Errorf("expected counts %d/%d, found %d/%d", s.Count, s.NaNCount, stored.Count, stored.NaNCount)
+	}
+}
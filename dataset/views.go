@@ -0,0 +1,116 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+)
+
+// Masked returns a lazy view of r with pixels set to NaN where the aligned mask is NaN or 0.
+func Masked(r Reader, mask Reader) (Reader, error) {
+	if err := CheckAligned(r.ImageParams(), mask.ImageParams()); err != nil {
+		return nil, err
+	}
+	return view(r, r.ImageParams(), func(box modis.Box) ([]float64, error) {
+		res, err := r.ReadBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		m, err := mask.ReadBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range m {
+			if math.IsNaN(v) || v == 0 {
+				res[i] = math.NaN()
+			}
+		}
+		return res, nil
+	}), nil
+}
+
+// Map returns a lazy view of r with fn applied to every pixel value, including NaN. The view
+// reports Float64 values without scale and offset as fn may change the range, e.g. converting
+// LST from Kelvin to Celsius.
+func Map(r Reader, fn func(v float64) float64) Reader {
+	res, _ := MapN(func(vs []float64) float64 { return fn(vs[0]) }, r)
+	return res
+}
+
+// MapN returns a lazy view combining the pixel values of aligned readers with fn, which receives
+// the values in the order of the readers. Like Map, the view reports Float64 values without
+// scale and offset.
+func MapN(fn func(vs []float64) float64, rs ...Reader) (Reader, error) {
+	if len(rs) == 0 {
+		return nil, fmt.Errorf("no readers to map")
+	}
+	var params []*modis.ImageParams
+	for _, r := range rs {
+		params = append(params, r.ImageParams())
+	}
+	if err := CheckAligned(params...); err != nil {
+		return nil, err
+	}
+	p := rs[0].ImageParams().ToBuilder().DataType(gdal.Float64).Scale(1).Offset(0).Build()
+	return view(rs[0], p, func(box modis.Box) ([]float64, error) {
+		buffers := make([][]float64, len(rs))
+		for i, r := range rs {
+			var err error
+			if buffers[i], err = r.ReadBlock(0, 0, box); err != nil {
+				return nil, err
+			}
+		}
+		res := make([]float64, box[2]*box[3])
+		vs := make([]float64, len(rs))
+		for j := range res {
+			for i := range buffers {
+				vs[i] = buffers[i][j]
+			}
+			res[j] = fn(vs)
+		}
+		return res, nil
+	}), nil
+}
+
+// Replace returns a lazy view of r with all values equal to from replaced by to, where NaN
+// matches NaN. The image parameters of r are kept, so to should be representable by them
+// if the view is written out.
+func Replace(r Reader, from, to float64) Reader {
+	return view(r, r.ImageParams(), func(box modis.Box) ([]float64, error) {
+		res, err := r.ReadBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range res {
+			if v == from || (math.IsNaN(v) && math.IsNaN(from)) {
+				res[i] = to
+			}
+		}
+		return res, nil
+	})
+}
+
+// Clamp returns a lazy view of r with values limited to [min, max], NaN remains NaN.
+func Clamp(r Reader, min, max float64) (Reader, error) {
+	if !(min <= max) {
+		return nil, fmt.Errorf("invalid range [%v,%v]", min, max)
+	}
+	return view(r, r.ImageParams(), func(box modis.Box) ([]float64, error) {
+		res, err := r.ReadBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		for i, v := range res {
+			res[i] = math.Max(min, math.Min(max, v))
+		}
+		return res, nil
+	}), nil
+}
+
+// view returns a derived reader over the full grid of r reading blocks of the size of those of r.
+func view(r Reader, p *modis.ImageParams, block func(box modis.Box) ([]float64, error)) *derived {
+	bx, by := r.BlockSize()
+	return &derived{p: p, bx: bx, by: by, block: block}
+}
@@ -0,0 +1,83 @@
+package dataset_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestViews(t *testing.T) {
+	p := modis.ImageParamsBuilder(4, 3).DataType(gdal.Int16).Scale(0.02).Build()
+	r := dataset.NewInMemory(p)
+	fillSequence(t, r)
+	_ = r.Write(3, 2, math.NaN())
+	mask := dataset.NewInMemory(p)
+	for x := 0; x < 4; x++ {
+		for y := 0; y < 3; y++ {
+			_ = mask.Write(x, y, float64(x%2))
+		}
+	}
+
+	masked, err := dataset.Masked(r, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValues(t, "masked", masked, []float64{nan, 1, nan, 3, nan, 5, nan, 7, nan, 9, nan, nan})
+	if masked.ImageParams().DataType() != gdal.Int16 {
+		t.Errorf("expected data type to be kept")
+	}
+
+	mapped := dataset.Map(r, func(v float64) float64 { return v - 273.15 })
+	assertValues(t, "mapped", mapped, []float64{-273.15, -272.15, -271.15, -270.15, -269.15, -268.15, -267.15, -266.15, -265.15, -264.15, -263.15, nan})
+	if mp := mapped.ImageParams(); mp.DataType() != gdal.Float64 || mp.Scale() != 1 || mp.Transform() != p.Transform() {
+		t.Errorf("unexpected mapped parameters")
+	}
+
+	sum, err := dataset.MapN(func(vs []float64) float64 { return vs[0] + vs[1] }, r, mask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValues(t, "sum", sum, []float64{0, 2, 2, 4, 4, 6, 6, 8, 8, 10, 10, nan})
+
+	assertValues(t, "replaced", dataset.Replace(r, math.NaN(), -1), []float64{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, -1})
+
+	clamped, err := dataset.Clamp(r, 2, 8)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertValues(t, "clamped", clamped, []float64{2, 2, 2, 3, 4, 5, 6, 7, 8, 8, 8, nan})
+	if _, err = dataset.Clamp(r, 1, 0); err == nil {
+		t.Error("expected error for invalid range")
+	}
+
+	other := dataset.NewInMemory(modis.ImageParamsBuilder(4, 4).Build())
+	if _, err = dataset.Masked(r, other); err == nil {
+		t.Error("expected error for masked with different grid")
+	}
+	if _, err = dataset.MapN(func(vs []float64) float64 { return vs[0] }, r, other); err == nil {
+		t.Error("expected error for map with different grid")
+	}
+}
+
+var nan = math.NaN()
+
+func assertValues(t *testing.T, name string, r dataset.Reader, expected []float64) {
+	p := r.ImageParams()
+	// read in two parts to exercise offsets
+	top, err := r.ReadBlock(0, 0, modis.Box{0, 0, p.XSize(), 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rest, err := r.ReadBlock(0, 1, modis.Box{0, 0, p.XSize(), p.YSize() - 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, v := range append(top, rest...) {
+		if e := expected[i]; !(math.IsNaN(e) && math.IsNaN(v)) && math.Abs(e-v) > 1e-9 {
+			t.Errorf("%s: expected %v at %d, found %v", name, e, i, v)
+		}
+	}
+}
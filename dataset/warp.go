@@ -0,0 +1,355 @@
+package dataset
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// Resampling defines how values are interpolated when warping.
+type Resampling int
+
+const (
+	Nearest Resampling = iota
+	Bilinear
+	Cubic
+	// Average takes the mean of all valid source pixels within the footprint of the target pixel.
+	Average
+	// Mode takes the most frequent valid source value within the footprint of the target pixel.
+	Mode
+)
+
+const (
+	warpBlockLines = 64
+	// warpEdgeSamples defines the number of points per edge used to find the extent of a warped image.
+	warpEdgeSamples = 50
+)
+
+// WarpParams derives parameters of an image covering the extent of src in the coordinate system
+// given by srs (e.g. "EPSG:3035", WKT or PROJ definition) with square pixels of given size in
+// the units of srs. All other parameters are copied from src.
+func WarpParams(src *modis.ImageParams, srs string, resolution float64) (*modis.ImageParams, error) {
+	if resolution <= 0 {
+		return nil, fmt.Errorf("positive resolution required, found %v", resolution)
+	}
+	to := gdal.CreateSpatialReference("")
+	defer to.Destroy()
+	if err := to.SetFromUserInput(srs); err != nil {
+		return nil, err
+	}
+	wkt, err := to.ToWKT()
+	if err != nil {
+		return nil, err
+	}
+	ct := newPixelTransform(src.Projection(), wkt, src.Transform())
+	defer ct.destroy()
+	// sample the boundary of the source image
+	var xs, ys []float64
+	nx, ny := float64(src.XSize()), float64(src.YSize())
+	for i := 0; i <= warpEdgeSamples; i++ {
+		f := float64(i) / warpEdgeSamples
+		xs = append(xs, f*nx, f*nx, 0, nx)
+		ys = append(ys, 0, ny, f*ny, f*ny)
+	}
+	xs, ys = ct.forward(xs, ys)
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i := range xs {
+		if !num.IsFinite(xs[i]) || !num.IsFinite(ys[i]) {
+			continue
+		}
+		minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+		minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+	}
+	if minX > maxX || minY > maxY {
+		return nil, fmt.Errorf("failed to transform image extent into %s", srs)
+	}
+	xSize := int(math.Ceil((maxX - minX) / resolution))
+	ySize := int(math.Ceil((maxY - minY) / resolution))
+	return src.ToBuilder().
+		Size(num.MaxInt(xSize, 1), num.MaxInt(ySize, 1)).
+		Transform(modis.AffineTransform{minX, resolution, 0, maxY, 0, -resolution}).
+		Projection(wkt).
+		Build(), nil
+}
+
+// Warp resamples src onto the grid of dst (given by its image parameters) block by block,
+// target pixels without valid source values are set to NaN (NoData).
+func Warp(src Reader, dst Writer, alg Resampling) error {
+	dp := dst.ImageParams()
+	ct := newPixelTransform(dp.Projection(), src.ImageParams().Projection(), dp.Transform())
+	defer ct.destroy()
+	sinv, err := src.ImageParams().Transform().Invert()
+	if err != nil {
+		return err
+	}
+	bx, by := dp.XSize(), warpBlockLines
+	if r, ok := dst.(Reader); ok {
+		bx, by = r.BlockSize()
+	}
+	for _, box := range blockBoxes(dp, bx, by) {
+		buffer, err := warpBlock(src, ct, sinv, box, alg)
+		if err != nil {
+			return err
+		}
+		if err = dst.WriteBlock(0, 0, box, buffer); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WarpToMemory resamples src onto the grid given by p into a new in-memory dataset.
+func WarpToMemory(src Reader, p *modis.ImageParams, alg Resampling) (*inMemory, error) {
+	res := NewInMemory(p)
+	if err := Warp(src, res, alg); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// warpBlock computes the target block: pixel corners of the block are transformed into source
+// pixel coordinates, the source window covering them is read and the values resampled.
+func warpBlock(src Reader, ct *pixelTransform, sinv modis.AffineTransform, box modis.Box, alg Resampling) ([]float64, error) {
+	w, h := box[2], box[3]
+	// corners (w+1)x(h+1) followed by centres wxh
+	var xs, ys []float64
+	for j := 0; j <= h; j++ {
+		for i := 0; i <= w; i++ {
+			xs = append(xs, float64(box[0]+i))
+			ys = append(ys, float64(box[1]+j))
+		}
+	}
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			xs = append(xs, float64(box[0]+i)+0.5)
+			ys = append(ys, float64(box[1]+j)+0.5)
+		}
+	}
+	xs, ys = ct.forward(xs, ys)
+	for i := range xs {
+		xs[i], ys[i] = applyTransform(sinv, xs[i], ys[i])
+	}
+	res := make([]float64, w*h)
+	for i := range res {
+		res[i] = math.NaN()
+	}
+	sp := src.ImageParams()
+	minX, minY, maxX, maxY := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+	for i := range xs {
+		if num.IsFinite(xs[i]) && num.IsFinite(ys[i]) {
+			minX, maxX = math.Min(minX, xs[i]), math.Max(maxX, xs[i])
+			minY, maxY = math.Min(minY, ys[i]), math.Max(maxY, ys[i])
+		}
+	}
+	// the margin covers the cubic kernel
+	x0, y0 := num.MaxInt(int(math.Floor(minX))-2, 0), num.MaxInt(int(math.Floor(minY))-2, 0)
+	x1, y1 := num.MinInt(int(math.Ceil(maxX))+2, sp.XSize()), num.MinInt(int(math.Ceil(maxY))+2, sp.YSize())
+	if x1 <= x0 || y1 <= y0 {
+		return res, nil
+	}
+	win := &window{box: modis.Box{x0, y0, x1 - x0, y1 - y0}}
+	var err error
+	if win.data, err = src.ReadBlock(0, 0, win.box); err != nil {
+		return nil, err
+	}
+	corners := (w + 1) * (h + 1)
+	for j := 0; j < h; j++ {
+		for i := 0; i < w; i++ {
+			k := corners + j*w + i
+			if !num.IsFinite(xs[k]) || !num.IsFinite(ys[k]) {
+				continue
+			}
+			switch alg {
+			case Bilinear:
+				res[j*w+i] = win.bilinear(xs[k], ys[k])
+			case Cubic:
+				res[j*w+i] = win.cubic(xs[k], ys[k])
+			case Average, Mode:
+				fx0, fy0, fx1, fy1 := math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)
+				for _, c := range []int{j*(w+1) + i, j*(w+1) + i + 1, (j+1)*(w+1) + i, (j+1)*(w+1) + i + 1} {
+					fx0, fx1 = math.Min(fx0, xs[c]), math.Max(fx1, xs[c])
+					fy0, fy1 = math.Min(fy0, ys[c]), math.Max(fy1, ys[c])
+				}
+				res[j*w+i] = win.footprint(xs[k], ys[k], fx0, fy0, fx1, fy1, alg == Mode)
+			default:
+				res[j*w+i] = win.at(int(math.Floor(xs[k])), int(math.Floor(ys[k])))
+			}
+		}
+	}
+	return res, nil
+}
+
+// window holds source values for a box, pixel coordinates are in the source image.
+type window struct {
+	box  modis.Box
+	data []float64
+}
+
+func (w *window) at(x, y int) float64 {
+	x, y = x-w.box[0], y-w.box[1]
+	if x < 0 || y < 0 || x >= w.box[2] || y >= w.box[3] {
+		return math.NaN()
+	}
+	return w.data[y*w.box[2]+x]
+}
+
+// bilinear interpolates between the 4 nearest pixel centres ignoring NaN values.
+func (w *window) bilinear(x, y float64) float64 {
+	u, v := x-0.5, y-0.5
+	i0, j0 := int(math.Floor(u)), int(math.Floor(v))
+	fx, fy := u-float64(i0), v-float64(j0)
+	sum, weights := 0.0, 0.0
+	for dj := 0; dj < 2; dj++ {
+		for di := 0; di < 2; di++ {
+			val := w.at(i0+di, j0+dj)
+			if math.IsNaN(val) {
+				continue
+			}
+			wt := (1 - math.Abs(float64(di)-fx)) * (1 - math.Abs(float64(dj)-fy))
+			sum += wt * val
+			weights += wt
+		}
+	}
+	if weights == 0 {
+		return math.NaN()
+	}
+	return sum / weights
+}
+
+// cubic interpolates over the 4x4 nearest pixel centres with the Keys kernel (a=-0.5), falling
+// back to bilinear interpolation if any of them is NaN.
+func (w *window) cubic(x, y float64) float64 {
+	u, v := x-0.5, y-0.5
+	i0, j0 := int(math.Floor(u)), int(math.Floor(v))
+	fx, fy := u-float64(i0), v-float64(j0)
+	sum := 0.0
+	for dj := -1; dj <= 2; dj++ {
+		for di := -1; di <= 2; di++ {
+			val := w.at(i0+di, j0+dj)
+			if math.IsNaN(val) {
+				return w.bilinear(x, y)
+			}
+			sum += keys(float64(di)-fx) * keys(float64(dj)-fy) * val
+		}
+	}
+	return sum
+}
+
+func keys(t float64) float64 {
+	const a = -0.5
+	t = math.Abs(t)
+	switch {
+	case t <= 1:
+		return (a+2)*t*t*t - (a+3)*t*t + 1
+	case t < 2:
+		return a*t*t*t - 5*a*t*t + 8*a*t - 4*a
+	}
+	return 0
+}
+
+// footprint aggregates valid source pixels with centres within [x0,x1)x[y0,y1), falling back
+// to the nearest pixel of (x,y) for footprints smaller than a pixel.
+func (w *window) footprint(x, y, x0, y0, x1, y1 float64, mode bool) float64 {
+	i0, i1 := int(math.Floor(x0+0.5)), int(math.Floor(x1+0.5))
+	j0, j1 := int(math.Floor(y0+0.5)), int(math.Floor(y1+0.5))
+	if i1 <= i0 || j1 <= j0 {
+		return w.at(int(math.Floor(x)), int(math.Floor(y)))
+	}
+	var values []float64
+	for j := j0; j < j1; j++ {
+		for i := i0; i < i1; i++ {
+			if val := w.at(i, j); !math.IsNaN(val) {
+				values = append(values, val)
+			}
+		}
+	}
+	if len(values) == 0 {
+		return math.NaN()
+	}
+	if mode {
+		return modeOf(values)
+	}
+	sum := 0.0
+	for _, val := range values {
+		sum += val
+	}
+	return sum / float64(len(values))
+}
+
+// modeOf returns the most frequent value, the smallest one on ties.
+func modeOf(values []float64) float64 {
+	sort.Float64s(values)
+	res, best := values[0], 0
+	for i := 0; i < len(values); {
+		j := i
+		for j < len(values) && values[j] == values[i] {
+			j++
+		}
+		if j-i > best {
+			res, best = values[i], j-i
+		}
+		i = j
+	}
+	return res
+}
+
+// pixelTransform transforms pixel coordinates of one image into the coordinate system of another.
+type pixelTransform struct {
+	at       modis.AffineTransform
+	identity bool
+	from, to gdal.SpatialReference
+	ct       gdal.CoordinateTransform
+}
+
+func newPixelTransform(fromWKT, toWKT string, at modis.AffineTransform) *pixelTransform {
+	res := &pixelTransform{at: at}
+	if fromWKT == toWKT || sameProjection(fromWKT, toWKT) {
+		res.identity = true
+		return res
+	}
+	res.from = gdal.CreateSpatialReference(fromWKT)
+	res.to = gdal.CreateSpatialReference(toWKT)
+	res.ct = gdal.CreateCoordinateTransform(res.from, res.to)
+	return res
+}
+
+// forward converts pixel coordinates into target coordinates, failed points are NaN.
+func (pt *pixelTransform) forward(xs, ys []float64) ([]float64, []float64) {
+	for i := range xs {
+		xs[i], ys[i] = applyTransform(pt.at, xs[i], ys[i])
+	}
+	if pt.identity || len(xs) == 0 {
+		return xs, ys
+	}
+	// the transform overwrites points that succeeded even if others fail, so keep the originals
+	xs0, ys0 := append([]float64(nil), xs...), append([]float64(nil), ys...)
+	zs := make([]float64, len(xs))
+	if !pt.ct.Transform(len(xs), xs, ys, zs) {
+		// transform point by point to find the failing ones
+		for i := range xs {
+			x, y, z := []float64{xs0[i]}, []float64{ys0[i]}, []float64{0}
+			if pt.ct.Transform(1, x, y, z) {
+				xs[i], ys[i] = x[0], y[0]
+			} else {
+				xs[i], ys[i] = math.NaN(), math.NaN()
+			}
+		}
+	}
+	return xs, ys
+}
+
+func (pt *pixelTransform) destroy() {
+	if !pt.identity {
+		pt.ct.Destroy()
+		pt.from.Destroy()
+		pt.to.Destroy()
+	}
+}
+
+func applyTransform(at modis.AffineTransform, x, y float64) (float64, float64) {
+	return at[0] + x*at[1] + y*at[2], at[3] + x*at[4] + y*at[5]
+}
@@ -0,0 +1,157 @@
+package dataset_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+func TestWarp_SameProjection(t *testing.T) {
+	p := modis.ImageParamsBuilder(20, 100).Transform(modis.AffineTransform{1000, 10, 0, 5000, 0, -10}).Build()
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	_ = src.Write(4, 4, math.NaN())
+
+	res, err := dataset.WarpToMemory(src, p, dataset.Nearest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 20; x++ {
+			expected, _ := src.Read(x, y)
+			if v, _ := res.Read(x, y); v != expected && !(math.IsNaN(v) && math.IsNaN(expected)) {
+				t.Fatalf("nearest: expected %v at (%d,%d), found %v", expected, x, y, v)
+			}
+		}
+	}
+
+	// a grid shifted by half a pixel interpolates between neighbours of a linear ramp
+	shifted := p.ToBuilder().Size(19, 99).Transform(modis.AffineTransform{1005, 10, 0, 4995, 0, -10}).Build()
+	res, err = dataset.WarpToMemory(src, shifted, dataset.Bilinear)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.Read(10, 10); math.Abs(v-(10.5*20+10.5)) > 1e-9 {
+		t.Errorf("bilinear: expected %v, found %v", 10.5*20+10.5, v)
+	}
+	// NaN neighbours are ignored
+	if v, _ := res.Read(3, 3); math.IsNaN(v) {
+		t.Error("bilinear: expected a value next to NaN")
+	}
+	res, err = dataset.WarpToMemory(src, shifted, dataset.Cubic)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, _ := res.Read(10, 10); math.Abs(v-(10.5*20+10.5)) > 1e-9 {
+		t.Errorf("cubic: expected %v, found %v", 10.5*20+10.5, v)
+	}
+}
+
+func TestWarp_Coarser(t *testing.T) {
+	p := modis.ImageParamsBuilder(4, 4).Transform(modis.AffineTransform{0, 1, 0, 4, 0, -1}).Build()
+	src := dataset.NewInMemory(p)
+	values := []float64{
+		1, 1, 5, 6,
+		2, 1, 7, math.NaN(),
+		3, 3, math.NaN(), math.NaN(),
+		4, 3, math.NaN(), math.NaN(),
+	}
+	if err := src.WriteBlock(0, 0, modis.Box{0, 0, 4, 4}, values); err != nil {
+		t.Fatal(err)
+	}
+	coarse := p.ToBuilder().Size(2, 2).Transform(modis.AffineTransform{0, 2, 0, 4, 0, -2}).Build()
+
+	avg, err := dataset.WarpToMemory(src, coarse, dataset.Average)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mode, err := dataset.WarpToMemory(src, coarse, dataset.Mode)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][2]float64{{1.25, 1}, {6, 5}, {3.25, 3}, {math.NaN(), math.NaN()}}
+	for i, e := range expected {
+		x, y := i%2, i/2
+		a, _ := avg.Read(x, y)
+		m, _ := mode.Read(x, y)
+		if math.IsNaN(e[0]) {
+			if !math.IsNaN(a) || !math.IsNaN(m) {
+				t.Errorf("expected NaN at (%d,%d), found %v, %v", x, y, a, m)
+			}
+		} else if a != e[0] || m != e[1] {
+			t.Errorf("expected %v, %v at (%d,%d), found %v, %v", e[0], e[1], x, y, a, m)
+		}
+	}
+}
+
+func TestWarp_UnprojectablePoints(t *testing.T) {
+	sr := gdal.CreateSpatialReference("")
+	defer sr.Destroy()
+	_ = sr.FromEPSG(4326)
+	wkt, _ := sr.ToWKT()
+	// one column covering all longitudes with rows of one degree of latitude from the pole
+	p := modis.ImageParamsBuilder(1, 90).Transform(modis.AffineTransform{-1000, 2000, 0, 90, 0, -1}).Projection(wkt).Build()
+	src := dataset.NewInMemory(p)
+	for y := 0; y < 90; y++ {
+		_ = src.Write(0, y, float64(y))
+	}
+	// sinusoidal grid whose top row lies beyond the pole and cannot be transformed
+	pole := 6371007.181 * math.Pi / 2
+	dp := testParams().ToBuilder().Size(2, 4).Transform(modis.AffineTransform{0, 1e5, 0, pole + 0.8e5, 0, -1e5}).Build()
+	res, err := dataset.WarpToMemory(src, dp, dataset.Nearest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for y := 0; y < 4; y++ {
+		for x := 0; x < 2; x++ {
+			v, _ := res.Read(x, y)
+			if y == 0 && !math.IsNaN(v) {
+				t.Errorf("expected NaN beyond the pole at (%d,%d), found %v", x, y, v)
+			}
+			if y > 0 && v != float64(y-1) {
+				t.Errorf("expected %v at (%d,%d), found %v", y-1, x, y, v)
+			}
+		}
+	}
+}
+
+func TestWarpParams(t *testing.T) {
+	p := testParams()
+	res, err := dataset.WarpParams(p, "EPSG:4326", 0.005)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.DataType() != p.DataType() || res.Scale() != p.Scale() || res.Projection() == p.Projection() {
+		t.Errorf("unexpected parameters %v", res)
+	}
+	nw, se := p.NorthWest(), p.SouthEast()
+	top := res.Transform()[3]
+	bottom := top + float64(res.YSize())*res.Transform()[5]
+	if top < nw[0]-1e-9 || bottom > se[0] {
+		t.Errorf("expected latitude extent to cover [%v,%v], found [%v,%v]", se[0], nw[0], bottom, top)
+	}
+	if _, err = dataset.WarpParams(p, "EPSG:4326", 0); err == nil {
+		t.Error("expected error for zero resolution")
+	}
+
+	src := dataset.NewInMemory(p)
+	fillSequence(t, src)
+	dst, err := dataset.WarpToMemory(src, res, dataset.Nearest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := 0
+	for y := 0; y < res.YSize(); y++ {
+		for x := 0; x < res.XSize(); x++ {
+			if v, _ := dst.Read(x, y); !math.IsNaN(v) {
+				valid++
+			}
+		}
+	}
+	if valid == 0 {
+		t.Error("expected valid values in the warped image")
+	}
+}
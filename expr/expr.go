@@ -0,0 +1,352 @@
+// Package expr evaluates raster algebra expressions such as "(lst_day - lst_night)",
+// "ndvi > 0.3 ? 1 : nan" or "max(b1, b2) / sqrt(b3)" over named aligned readers.
+//
+// Expressions support arithmetic (+ - * / % ^), comparisons (< <= > >= == !=), logical
+// operators (&& || !) and the conditional operator (c ? a : b); comparisons and logical
+// operators yield 1 or 0. NaN propagates through all operators and functions except isnan,
+// a NaN condition yields NaN.
+package expr
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+// Expr is a parsed expression.
+type Expr struct {
+	text string
+	root node
+	vars []string
+}
+
+// Parse parses an expression.
+func Parse(text string) (*Expr, error) {
+	tokens, err := tokenize(text)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	root, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokEOF {
+		return nil, p.errorf("unexpected input")
+	}
+	vars := make(map[string]bool)
+	root.collect(vars)
+	res := &Expr{text: text, root: root}
+	for name := range vars {
+		res.vars = append(res.vars, name)
+	}
+	sort.Strings(res.vars)
+	return res, nil
+}
+
+func (e *Expr) String() string {
+	return e.text
+}
+
+// Vars returns the sorted names of the variables used in the expression.
+func (e *Expr) Vars() []string {
+	return append([]string(nil), e.vars...)
+}
+
+// Value evaluates the expression for scalar variable values.
+func (e *Expr) Value(vars map[string]float64) (float64, error) {
+	env := make(map[string][]float64)
+	for _, name := range e.vars {
+		v, ok := vars[name]
+		if !ok {
+			return math.NaN(), fmt.Errorf("variable %s is not defined", name)
+		}
+		env[name] = []float64{v}
+	}
+	return e.root.eval(env, 1)[0], nil
+}
+
+// Eval evaluates the expression block by block over aligned readers given by variable name and
+// writes the result to dst, see dataset.Apply for the options.
+func (e *Expr) Eval(ctx context.Context, dst dataset.Writer, readers map[string]dataset.Reader, opts *dataset.ApplyOptions) error {
+	var srcs []dataset.Reader
+	for _, name := range e.vars {
+		r, ok := readers[name]
+		if !ok {
+			return fmt.Errorf("no reader for variable %s", name)
+		}
+		srcs = append(srcs, r)
+	}
+	if len(srcs) == 0 {
+		// constant expression: use any reader to drive the iteration
+		for _, r := range readers {
+			srcs = append(srcs, r)
+			break
+		}
+	}
+	if opts != nil && opts.Halo != 0 {
+		return fmt.Errorf("expressions are evaluated pixel by pixel, halo not supported")
+	}
+	fn := func(box modis.Box, in [][]float64, out []float64) error {
+		env := make(map[string][]float64)
+		for i, name := range e.vars {
+			env[name] = in[i]
+		}
+		copy(out, e.root.eval(env, len(out)))
+		return nil
+	}
+	return dataset.Apply(ctx, dst, fn, opts, srcs...)
+}
+
+// ToFile evaluates the expression like Eval and writes the result into a new Float32 file on
+// the grid of the readers (with NaN as NoData) created with dataset.New. The file is discarded
+// if the evaluation fails.
+func (e *Expr) ToFile(ctx context.Context, fileName string, driver dataset.Driver, readers map[string]dataset.Reader, copts *dataset.CreateOptions, opts *dataset.ApplyOptions) error {
+	var grid *modis.ImageParams
+	for _, name := range e.vars {
+		if r, ok := readers[name]; ok {
+			grid = r.ImageParams()
+			break
+		}
+	}
+	if grid == nil && len(e.vars) == 0 {
+		// constant expression: use the grid of any reader as Eval does
+		for _, r := range readers {
+			grid = r.ImageParams()
+			break
+		}
+	}
+	if grid == nil {
+		return fmt.Errorf("no reader for the grid of %s", e.text)
+	}
+	p := grid.ToBuilder().DataType(gdal.Float32).Scale(1).Offset(0).NaN(math.NaN()).Build()
+	w, err := dataset.New(fileName, driver, p, copts)
+	if err != nil {
+		return err
+	}
+	if err = e.Eval(ctx, w, readers, opts); err != nil {
+		_ = dataset.Discard(w)
+		return err
+	}
+	return w.Close()
+}
+
+type node interface {
+	eval(env map[string][]float64, n int) []float64
+	collect(vars map[string]bool)
+}
+
+type constNode float64
+
+func (c constNode) eval(env map[string][]float64, n int) []float64 {
+	res := make([]float64, n)
+	for i := range res {
+		res[i] = float64(c)
+	}
+	return res
+}
+
+func (c constNode) collect(vars map[string]bool) {}
+
+type varNode string
+
+func (v varNode) eval(env map[string][]float64, n int) []float64 {
+	return env[string(v)]
+}
+
+func (v varNode) collect(vars map[string]bool) {
+	vars[string(v)] = true
+}
+
+type unaryNode struct {
+	op string
+	a  node
+}
+
+func (u *unaryNode) eval(env map[string][]float64, n int) []float64 {
+	a := u.a.eval(env, n)
+	res := make([]float64, n)
+	for i, v := range a {
+		switch {
+		case u.op == "-":
+			res[i] = -v
+		case math.IsNaN(v):
+			res[i] = v
+		default:
+			res[i] = bool2float(v == 0)
+		}
+	}
+	return res
+}
+
+func (u *unaryNode) collect(vars map[string]bool) {
+	u.a.collect(vars)
+}
+
+type binaryNode struct {
+	op   string
+	a, b node
+}
+
+func (bn *binaryNode) eval(env map[string][]float64, n int) []float64 {
+	a := bn.a.eval(env, n)
+	b := bn.b.eval(env, n)
+	res := make([]float64, n)
+	for i := range res {
+		x, y := a[i], b[i]
+		if math.IsNaN(x) || math.IsNaN(y) {
+			res[i] = math.NaN()
+			continue
+		}
+		switch bn.op {
+		case "+":
+			res[i] = x + y
+		case "-":
+			res[i] = x - y
+		case "*":
+			res[i] = x * y
+		case "/":
+			res[i] = x / y
+		case "%":
+			res[i] = math.Mod(x, y)
+		case "^":
+			res[i] = math.Pow(x, y)
+		case "<":
+			res[i] = bool2float(x < y)
+		case "<=":
+			res[i] = bool2float(x <= y)
+		case ">":
+			res[i] = bool2float(x > y)
+		case ">=":
+			res[i] = bool2float(x >= y)
+		case "==":
+			res[i] = bool2float(x == y)
+		case "!=":
+			res[i] = bool2float(x != y)
+		case "&&":
+			res[i] = bool2float(x != 0 && y != 0)
+		case "||":
+			res[i] = bool2float(x != 0 || y != 0)
+		}
+	}
+	return res
+}
+
+func (bn *binaryNode) collect(vars map[string]bool) {
+	bn.a.collect(vars)
+	bn.b.collect(vars)
+}
+
+type condNode struct {
+	cond, a, b node
+}
+
+func (c *condNode) eval(env map[string][]float64, n int) []float64 {
+	cond := c.cond.eval(env, n)
+	a := c.a.eval(env, n)
+	b := c.b.eval(env, n)
+	res := make([]float64, n)
+	for i, v := range cond {
+		switch {
+		case math.IsNaN(v):
+			res[i] = v
+		case v != 0:
+			res[i] = a[i]
+		default:
+			res[i] = b[i]
+		}
+	}
+	return res
+}
+
+func (c *condNode) collect(vars map[string]bool) {
+	c.cond.collect(vars)
+	c.a.collect(vars)
+	c.b.collect(vars)
+}
+
+type function struct {
+	minArgs int
+	maxArgs int // -1 for variadic
+	apply   func(args []float64) float64
+}
+
+type callNode struct {
+	name string
+	fn   function
+	args []node
+}
+
+func (c *callNode) eval(env map[string][]float64, n int) []float64 {
+	args := make([][]float64, len(c.args))
+	for i, arg := range c.args {
+		args[i] = arg.eval(env, n)
+	}
+	res := make([]float64, n)
+	values := make([]float64, len(args))
+	for i := range res {
+		for j, arg := range args {
+			values[j] = arg[i]
+		}
+		res[i] = c.fn.apply(values)
+	}
+	return res
+}
+
+func (c *callNode) collect(vars map[string]bool) {
+	for _, arg := range c.args {
+		arg.collect(vars)
+	}
+}
+
+var constants = map[string]float64{
+	"nan": math.NaN(),
+	"inf": math.Inf(1),
+	"pi":  math.Pi,
+}
+
+func unary(fn func(float64) float64) function {
+	return function{minArgs: 1, maxArgs: 1, apply: func(args []float64) float64 { return fn(args[0]) }}
+}
+
+// extremum returns the min (sign < 0) or max (sign > 0) of the arguments propagating NaN.
+func extremum(sign float64) function {
+	return function{minArgs: 1, maxArgs: -1, apply: func(args []float64) float64 {
+		res := args[0]
+		for _, v := range args {
+			if math.IsNaN(v) {
+				return v
+			}
+			if (v-res)*sign > 0 {
+				res = v
+			}
+		}
+		return res
+	}}
+}
+
+var functions = map[string]function{
+	"min":   extremum(-1),
+	"max":   extremum(1),
+	"abs":   unary(math.Abs),
+	"sqrt":  unary(math.Sqrt),
+	"exp":   unary(math.Exp),
+	"log":   unary(math.Log),
+	"log10": unary(math.Log10),
+	"floor": unary(math.Floor),
+	"ceil":  unary(math.Ceil),
+	"round": unary(math.Round),
+	"isnan": unary(func(v float64) float64 { return bool2float(math.IsNaN(v)) }),
+}
+
+func bool2float(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,128 @@
+package expr_test
+
+import (
+	"context"
+	"math"
+	"path"
+	"reflect"
+	"testing"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/expr"
+)
+
+func TestExpr_Value(t *testing.T) {
+	vars := map[string]float64{"a": 3, "b": 4, "lst_day": 300, "lst_night": 280, "ndvi": 0.5, "n": math.NaN()}
+	cases := []struct {
+		text     string
+		expected float64
+	}{
+		{text: "1 + 2 * 3", expected: 7},
+		{text: "(1 + 2) * 3", expected: 9},
+		{text: "lst_day - lst_night", expected: 20},
+		{text: "b - a / (b + a)", expected: 4 - 3.0/7},
+		{text: "-a ^ 2", expected: -9},
+		{text: "2 ^ 3 ^ 2", expected: 512},
+		{text: "10 % 4", expected: 2},
+		{text: "1.5e2 + .5", expected: 150.5},
+		{text: "ndvi > 0.3 ? 1 : nan", expected: 1},
+		{text: "ndvi > 0.6 ? 1 : nan", expected: math.NaN()},
+		{text: "a < b && b <= 4 || 0", expected: 1},
+		{text: "!(a == 3)", expected: 0},
+		{text: "a != b ? a > b ? 1 : 2 : 3", expected: 2},
+		{text: "min(a, b, 5) + max(a, b)", expected: 7},
+		{text: "abs(a - b) + sqrt(b)", expected: 3},
+		{text: "n + 1", expected: math.NaN()},
+		{text: "n > 1", expected: math.NaN()},
+		{text: "n ? 1 : 0", expected: math.NaN()},
+		{text: "max(a, n)", expected: math.NaN()},
+		{text: "isnan(n) + isnan(a)", expected: 1},
+		{text: "round(pi * 100) / 100", expected: 3.14},
+	}
+	for _, c := range cases {
+		e, err := expr.Parse(c.text)
+		if err != nil {
+			t.Errorf("%s: %v", c.text, err)
+			continue
+		}
+		actual, err := e.Value(vars)
+		if err != nil {
+			t.Errorf("%s: %v", c.text, err)
+		} else if !(math.IsNaN(c.expected) && math.IsNaN(actual)) && math.Abs(actual-c.expected) > 1e-12 {
+			t.Errorf("%s: expected %v, found %v", c.text, c.expected, actual)
+		}
+	}
+}
+
+func TestExpr_ParseErrors(t *testing.T) {
+	for _, text := range []string{"", "1 +", "(a", "a b", "foo(a)", "min()", "abs(a, b)", "a ? b", "a # b", "a)"} {
+		if _, err := expr.Parse(text); err == nil {
+			t.Errorf("%q: expected error", text)
+		}
+	}
+	e, _ := expr.Parse("x + y * x")
+	if !reflect.DeepEqual(e.Vars(), []string{"x", "y"}) {
+		t.Errorf("expected vars [x y], found %v", e.Vars())
+	}
+	if _, err := e.Value(map[string]float64{"x": 1}); err == nil {
+		t.Error("expected error for undefined variable")
+	}
+}
+
+func TestExpr_Eval(t *testing.T) {
+	p := modis.ImageParamsBuilder(10, 100).Build()
+	day := dataset.NewInMemory(p)
+	night := dataset.NewInMemory(p)
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 10; x++ {
+			_ = day.Write(x, y, float64(300+x))
+			_ = night.Write(x, y, float64(280+y%7))
+		}
+	}
+	_ = day.Write(3, 3, math.NaN())
+	e, err := expr.Parse("lst_day - lst_night > 18 ? lst_day - lst_night : nan")
+	if err != nil {
+		t.Fatal(err)
+	}
+	readers := map[string]dataset.Reader{"lst_day": day, "lst_night": night}
+	fileName := path.Join(t.TempDir(), "diff.tif")
+	if err = e.ToFile(context.Background(), fileName, dataset.GTiff, readers, nil, &dataset.ApplyOptions{Workers: 2}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 10; x++ {
+			v, _ := r.Read(x, y)
+			diff := float64(300 + x - 280 - y%7)
+			if (x == 3 && y == 3) || diff <= 18 {
+				if !math.IsNaN(v) {
+					t.Errorf("expected NaN at (%d,%d), found %v", x, y, v)
+				}
+			} else if v != diff {
+				t.Errorf("expected %v at (%d,%d), found %v", diff, x, y, v)
+			}
+		}
+	}
+	if err = e.Eval(context.Background(), dataset.NewInMemory(p), map[string]dataset.Reader{"lst_day": day}, nil); err == nil {
+		t.Error("expected error for missing reader")
+	}
+
+	constant, _ := expr.Parse("1")
+	fileName = path.Join(t.TempDir(), "constant.tif")
+	if err = constant.ToFile(context.Background(), fileName, dataset.GTiff, readers, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	c, err := dataset.Open(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+	if v, _ := c.Read(9, 99); v != 1 {
+		t.Errorf("expected 1, found %v", v)
+	}
+}
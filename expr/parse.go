@@ -0,0 +1,286 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Grammar (lowest to highest precedence):
+//
+//	cond    = or [ "?" cond ":" cond ]
+//	or      = and { "||" and }
+//	and     = cmp { "&&" cmp }
+//	cmp     = sum [ ( "<" | "<=" | ">" | ">=" | "==" | "!=" ) sum ]
+//	sum     = product { ( "+" | "-" ) product }
+//	product = unary { ( "*" | "/" | "%" ) unary }
+//	unary   = ( "-" | "+" | "!" ) unary | power
+//	power   = primary [ "^" unary ]
+//	primary = number | "nan" | "inf" | name | name "(" cond { "," cond } ")" | "(" cond ")"
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokName
+	tokOp
+)
+
+type token struct {
+	kind tokenKind
+	text string
+	pos  int
+}
+
+func tokenize(s string) ([]token, error) {
+	var res []token
+	for i := 0; i < len(s); {
+		c := rune(s[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || c == '.':
+			j := i
+			for j < len(s) && (unicode.IsDigit(rune(s[j])) || s[j] == '.') {
+				j++
+			}
+			// exponent
+			if j < len(s) && (s[j] == 'e' || s[j] == 'E') {
+				k := j + 1
+				if k < len(s) && (s[k] == '+' || s[k] == '-') {
+					k++
+				}
+				if k < len(s) && unicode.IsDigit(rune(s[k])) {
+					for j = k; j < len(s) && unicode.IsDigit(rune(s[j])); j++ {
+					}
+				}
+			}
+			res = append(res, token{kind: tokNumber, text: s[i:j], pos: i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(s) && (unicode.IsLetter(rune(s[j])) || unicode.IsDigit(rune(s[j])) || s[j] == '_') {
+				j++
+			}
+			res = append(res, token{kind: tokName, text: s[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			if i+1 < len(s) {
+				switch s[i : i+2] {
+				case "<=", ">=", "==", "!=", "&&", "||":
+					op = s[i : i+2]
+				}
+			}
+			if op == "" && strings.ContainsRune("+-*/%^<>!?:(),", c) {
+				op = string(c)
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at %d", c, i)
+			}
+			res = append(res, token{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(res, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) accept(ops ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp {
+		return "", false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			p.pos++
+			return op, true
+		}
+	}
+	return "", false
+}
+
+func (p *parser) expect(op string) error {
+	if _, ok := p.accept(op); !ok {
+		return p.errorf("expected %q", op)
+	}
+	return nil
+}
+
+func (p *parser) errorf(format string, args ...interface{}) error {
+	t := p.peek()
+	found := t.text
+	if t.kind == tokEOF {
+		found = "end of expression"
+	}
+	return fmt.Errorf("%s at %d, found %s", fmt.Sprintf(format, args...), t.pos, found)
+}
+
+func (p *parser) cond() (node, error) {
+	c, err := p.or()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("?"); !ok {
+		return c, nil
+	}
+	a, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+	if err = p.expect(":"); err != nil {
+		return nil, err
+	}
+	b, err := p.cond()
+	if err != nil {
+		return nil, err
+	}
+	return &condNode{cond: c, a: a, b: b}, nil
+}
+
+// binaryLevel parses a left-associative sequence of operands joined by any of ops.
+func (p *parser) binaryLevel(next func() (node, error), ops ...string) (node, error) {
+	left, err := next()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept(ops...)
+		if !ok {
+			return left, nil
+		}
+		right, err := next()
+		if err != nil {
+			return nil, err
+		}
+		left = &binaryNode{op: op, a: left, b: right}
+	}
+}
+
+func (p *parser) or() (node, error) {
+	return p.binaryLevel(p.and, "||")
+}
+
+func (p *parser) and() (node, error) {
+	return p.binaryLevel(p.cmp, "&&")
+}
+
+func (p *parser) cmp() (node, error) {
+	left, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("<=", ">=", "==", "!=", "<", ">")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.sum()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: op, a: left, b: right}, nil
+}
+
+func (p *parser) sum() (node, error) {
+	return p.binaryLevel(p.product, "+", "-")
+}
+
+func (p *parser) product() (node, error) {
+	return p.binaryLevel(p.unary, "*", "/", "%")
+}
+
+func (p *parser) unary() (node, error) {
+	if op, ok := p.accept("-", "+", "!"); ok {
+		a, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		if op == "+" {
+			return a, nil
+		}
+		return &unaryNode{op: op, a: a}, nil
+	}
+	return p.power()
+}
+
+func (p *parser) power() (node, error) {
+	base, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := p.accept("^"); !ok {
+		return base, nil
+	}
+	exp, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+	return &binaryNode{op: "^", a: base, b: exp}, nil
+}
+
+func (p *parser) primary() (node, error) {
+	t := p.peek()
+	switch t.kind {
+	case tokNumber:
+		p.pos++
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s at %d", t.text, t.pos)
+		}
+		return constNode(v), nil
+	case tokName:
+		p.pos++
+		if _, ok := p.accept("("); ok {
+			return p.call(t)
+		}
+		if v, ok := constants[strings.ToLower(t.text)]; ok {
+			return constNode(v), nil
+		}
+		return varNode(t.text), nil
+	}
+	if _, ok := p.accept("("); ok {
+		res, err := p.cond()
+		if err != nil {
+			return nil, err
+		}
+		return res, p.expect(")")
+	}
+	return nil, p.errorf("expected a number, name or \"(\"")
+}
+
+func (p *parser) call(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, fmt.Errorf("unknown function %s at %d", name.text, name.pos)
+	}
+	var args []node
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.cond()
+			if err != nil {
+				return nil, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect(")"); err != nil {
+			return nil, err
+		}
+	}
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, fmt.Errorf("wrong number of arguments for %s at %d: %d", name.text, name.pos, len(args))
+	}
+	return &callNode{name: name.text, fn: fn, args: args}, nil
+}
@@ -0,0 +1,146 @@
+// Package extract extracts values at points from many images, e.g. station locations from all
+// granules listed by ts.ListAll, and streams them as table rows.
+package extract
+
+import (
+	"math"
+	"time"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/internal/num"
+	"github.com/nordicsense/modis/ts"
+)
+
+// Options define what is extracted besides the value at the point.
+type Options struct {
+	// Neighbourhood gives the size N of the NxN window around the point summarised in
+	// every row, no summary for N < 2.
+	Neighbourhood int
+}
+
+// Summary describes the valid values of the neighbourhood of a point.
+type Summary struct {
+	Valid int
+	Mean  float64
+	Std   float64
+	Min   float64
+	Max   float64
+}
+
+// Row is the extraction result for one point in one image.
+type Row struct {
+	// Dataset is the value dataset and Date the date of its image.
+	Dataset string
+	Date    time.Time
+	Point   Point
+	X, Y    int
+	Value   float64
+	// Time is the view time, zero if not available for the pixel.
+	Time          time.Time
+	Neighbourhood *Summary
+}
+
+// RowWriter receives extracted rows.
+type RowWriter interface {
+	Write(row *Row) error
+	Flush() error
+}
+
+// Extract extracts values and view times for all points from all pairs of datasets, opening
+// every dataset once, and writes rows in the order of pairs and points. Points outside of an
+// image are skipped.
+func Extract(pairs []ts.LayerPair, points []Point, opts *Options, out RowWriter) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	for _, pair := range pairs {
+		if err := extractPair(pair, points, opts, out); err != nil {
+			return err
+		}
+	}
+	return out.Flush()
+}
+
+func extractPair(pair ts.LayerPair, points []Point, opts *Options, out RowWriter) error {
+	values, err := dataset.Open(pair.Value)
+	if err != nil {
+		return err
+	}
+	defer values.Close()
+	times, err := dataset.Open(pair.Time)
+	if err != nil {
+		return err
+	}
+	defer times.Close()
+	p := values.ImageParams()
+	for _, point := range points {
+		if !p.Within(point.LatLon) {
+			continue
+		}
+		row, err := extractPoint(values, times, point, opts)
+		if err != nil {
+			return err
+		}
+		row.Dataset = pair.Value
+		if err = out.Write(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func extractPoint(values, times dataset.Reader, point Point, opts *Options) (*Row, error) {
+	p := values.ImageParams()
+	x, y := p.Transform().LatLon2Pixels(point.LatLon)
+	row := &Row{Date: p.Date(), Point: point, X: x, Y: y}
+	var err error
+	if row.Value, err = values.Read(x, y); err != nil {
+		return nil, err
+	}
+	if times.ImageParams().Within(point.LatLon) {
+		tv, err := times.ReadAtLatLon(point.LatLon)
+		if err != nil {
+			return nil, err
+		}
+		if !math.IsNaN(tv) {
+			if row.Time, err = times.ImageParams().Value2time(tv, point.LatLon); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if n := opts.Neighbourhood; n > 1 {
+		// the window is clipped to the image
+		x0, y0 := num.MaxInt(x-(n-1)/2, 0), num.MaxInt(y-(n-1)/2, 0)
+		x1, y1 := num.MinInt(x-(n-1)/2+n, p.XSize()), num.MinInt(y-(n-1)/2+n, p.YSize())
+		block, err := values.ReadBlock(0, 0, modis.Box{x0, y0, x1 - x0, y1 - y0})
+		if err != nil {
+			return nil, err
+		}
+		row.Neighbourhood = summarise(block)
+	}
+	return row, nil
+}
+
+func summarise(values []float64) *Summary {
+	res := &Summary{Mean: math.NaN(), Std: math.NaN(), Min: math.NaN(), Max: math.NaN()}
+	sum, sum2 := 0.0, 0.0
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		if res.Valid == 0 {
+			res.Min, res.Max = v, v
+		}
+		res.Valid++
+		sum += v
+		sum2 += v * v
+		res.Min, res.Max = math.Min(res.Min, v), math.Max(res.Max, v)
+	}
+	if res.Valid > 0 {
+		n := float64(res.Valid)
+		res.Mean = sum / n
+		res.Std = math.Sqrt(math.Max(sum2/n-res.Mean*res.Mean, 0))
+	}
+	return res
+}
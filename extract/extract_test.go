@@ -0,0 +1,108 @@
+package extract_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/extract"
+	"github.com/nordicsense/modis/ts"
+)
+
+func TestReadPoints(t *testing.T) {
+	points, err := extract.ReadPointsCSV(strings.NewReader("name,ID,Latitude,Longitude\nSodankylä,101,67.37,26.63\nAbisko,102, 68.35,18.82\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 2 || points[0].ID != "101" || points[1].LatLon != (modis.LatLon{68.35, 18.82}) {
+		t.Errorf("unexpected points %v", points)
+	}
+	if _, err = extract.ReadPointsCSV(strings.NewReader("id,lat\n1,2\n")); err == nil {
+		t.Error("expected error for missing column")
+	}
+	if _, err = extract.ReadPointsCSV(strings.NewReader("id,lat,lon\n1,2,x\n")); err == nil {
+		t.Error("expected error for invalid number")
+	}
+
+	points, err = extract.ReadPointsGeoJSON(strings.NewReader(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "properties": {"id": "a"}, "geometry": {"type": "Point", "coordinates": [26.63, 67.37]}},
+		{"type": "Feature", "id": 5, "properties": {}, "geometry": {"type": "Point", "coordinates": [18.82, 68.35]}},
+		{"type": "Feature", "geometry": {"type": "Point", "coordinates": [1, 2]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(points) != 3 || points[0].ID != "a" || points[1].ID != "5" || points[2].ID != "3" || points[0].LatLon != (modis.LatLon{67.37, 26.63}) {
+		t.Errorf("unexpected points %v", points)
+	}
+}
+
+func writeImage(t *testing.T, fileName string, p *modis.ImageParams, value func(x, y int) float64) {
+	w, err := dataset.New(fileName, dataset.GTiff, p, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	for y := 0; y < p.YSize(); y++ {
+		for x := 0; x < p.XSize(); x++ {
+			if err = w.Write(x, y, value(x, y)); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	date := time.Date(2020, 7, 1, 0, 0, 0, 0, time.UTC)
+	p := modis.ImageParamsBuilder(8, 6).
+		Transform(modis.AffineTransform{1111950, 926.625433, 0, 7783653, 0, -926.625433}).
+		Date(date).
+		Metadata("RANGEBEGINNINGDATE", "2020-07-01").
+		Build()
+	dir := t.TempDir()
+	pair := ts.LayerPair{Time: path.Join(dir, "time.tif"), Value: path.Join(dir, "value.tif")}
+	writeImage(t, pair.Value, p, func(x, y int) float64 { return float64(10*y + x) })
+	writeImage(t, pair.Time, p, func(x, y int) float64 { return 12 })
+
+	inside := extract.Point{ID: "in", LatLon: p.Transform().Pixels2LatLon(3, 2)}
+	outside := extract.Point{ID: "out", LatLon: modis.LatLon{10, 10}}
+	points := []extract.Point{inside, outside, {ID: "corner", LatLon: p.Transform().Pixels2LatLon(0, 0)}}
+	opts := &extract.Options{Neighbourhood: 3}
+
+	var buf bytes.Buffer
+	if err := extract.Extract([]ts.LayerPair{pair}, points, opts, extract.NewCSVWriter(&buf, opts)); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, found %q", buf.String())
+	}
+	if lines[0] != "dataset,date,id,lat,lon,x,y,value,time,n_valid,n_mean,n_std,n_min,n_max" {
+		t.Errorf("unexpected header %s", lines[0])
+	}
+	fields := strings.Split(lines[1], ",")
+	expectedTime := date.Add(time.Duration(modis.ModisLST2UTC(12, inside.LatLon[1]) * float64(time.Hour))).Format(time.RFC3339)
+	if fields[2] != "in" || fields[5] != "3" || fields[6] != "2" || fields[7] != "23" || fields[8] != expectedTime || fields[9] != "9" || fields[10] != "23" {
+		t.Errorf("unexpected row %s", lines[1])
+	}
+	// the neighbourhood is clipped at the image edge
+	if fields = strings.Split(lines[2], ","); fields[2] != "corner" || fields[9] != "4" || fields[10] != "5.5" {
+		t.Errorf("unexpected row %s", lines[2])
+	}
+
+	buf.Reset()
+	if err := extract.Extract([]ts.LayerPair{pair}, points[:1], nil, extract.NewJSONLWriter(&buf)); err != nil {
+		t.Fatal(err)
+	}
+	var row map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &row); err != nil {
+		t.Fatal(err)
+	}
+	if row["id"] != "in" || row["value"] != 23. || row["date"] != "2020-07-01" || row["neighbourhood"] != nil {
+		t.Errorf("unexpected row %v", row)
+	}
+}
@@ -0,0 +1,117 @@
+package extract
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/nordicsense/modis"
+)
+
+// Point is a named location in degrees.
+type Point struct {
+	ID     string
+	LatLon modis.LatLon
+}
+
+// ReadPoints reads points from a CSV file or, for the extensions .geojson and .json, from
+// a GeoJSON file.
+func ReadPoints(fileName string) ([]Point, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	switch strings.ToLower(filepath.Ext(fileName)) {
+	case ".geojson", ".json":
+		return ReadPointsGeoJSON(f)
+	}
+	return ReadPointsCSV(f)
+}
+
+// ReadPointsCSV reads points from CSV with a header naming the columns id, lat and lon
+// (case-insensitive, latitude and longitude are accepted as well), other columns are ignored.
+func ReadPointsCSV(r io.Reader) ([]Point, error) {
+	cr := csv.NewReader(r)
+	cr.TrimLeadingSpace = true
+	header, err := cr.Read()
+	if err != nil {
+		return nil, err
+	}
+	cols := map[string]int{"id": -1, "lat": -1, "lon": -1}
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "id":
+			cols["id"] = i
+		case "lat", "latitude":
+			cols["lat"] = i
+		case "lon", "long", "longitude":
+			cols["lon"] = i
+		}
+	}
+	for name, i := range cols {
+		if i < 0 {
+			return nil, fmt.Errorf("no %s column in header %v", name, header)
+		}
+	}
+	var res []Point
+	for line := 2; ; line++ {
+		record, err := cr.Read()
+		if err == io.EOF {
+			return res, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		lat, err := strconv.ParseFloat(strings.TrimSpace(record[cols["lat"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		lon, err := strconv.ParseFloat(strings.TrimSpace(record[cols["lon"]]), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		res = append(res, Point{ID: record[cols["id"]], LatLon: modis.LatLon{lat, lon}})
+	}
+}
+
+// ReadPointsGeoJSON reads Point features from a GeoJSON FeatureCollection. The ID is taken
+// from the "id" property, the feature id or else the 1-based position of the feature.
+func ReadPointsGeoJSON(r io.Reader) ([]Point, error) {
+	var doc struct {
+		Type     string `json:"type"`
+		Features []struct {
+			ID         interface{}            `json:"id"`
+			Properties map[string]interface{} `json:"properties"`
+			Geometry   *struct {
+				Type        string    `json:"type"`
+				Coordinates []float64 `json:"coordinates"`
+			} `json:"geometry"`
+		} `json:"features"`
+	}
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	if doc.Type != "FeatureCollection" {
+		return nil, fmt.Errorf("expected a FeatureCollection, found %s", doc.Type)
+	}
+	var res []Point
+	for i, f := range doc.Features {
+		if f.Geometry == nil || f.Geometry.Type != "Point" || len(f.Geometry.Coordinates) < 2 {
+			return nil, fmt.Errorf("feature %d: expected a Point geometry", i)
+		}
+		id := strconv.Itoa(i + 1)
+		if v, ok := f.Properties["id"]; ok && v != nil {
+			id = fmt.Sprint(v)
+		} else if f.ID != nil {
+			id = fmt.Sprint(f.ID)
+		}
+		res = append(res, Point{ID: id, LatLon: modis.LatLon{f.Geometry.Coordinates[1], f.Geometry.Coordinates[0]}})
+	}
+	return res, nil
+}
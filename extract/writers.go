@@ -0,0 +1,133 @@
+package extract
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/nordicsense/modis/internal/num"
+)
+
+var csvHeader = []string{"dataset", "date", "id", "lat", "lon", "x", "y", "value", "time"}
+
+var csvSummaryHeader = []string{"n_valid", "n_mean", "n_std", "n_min", "n_max"}
+
+type csvWriter struct {
+	w             *csv.Writer
+	neighbourhood bool
+	header        bool
+}
+
+// NewCSVWriter returns a writer of CSV rows with a header, with neighbourhood summary columns
+// if the neighbourhood is extracted. NaN values and missing times are written as empty fields.
+func NewCSVWriter(w io.Writer, opts *Options) RowWriter {
+	return &csvWriter{w: csv.NewWriter(w), neighbourhood: opts != nil && opts.Neighbourhood > 1}
+}
+
+func (cw *csvWriter) Write(row *Row) error {
+	if !cw.header {
+		header := csvHeader
+		if cw.neighbourhood {
+			header = append(append([]string(nil), csvHeader...), csvSummaryHeader...)
+		}
+		if err := cw.w.Write(header); err != nil {
+			return err
+		}
+		cw.header = true
+	}
+	record := []string{
+		row.Dataset,
+		row.Date.Format("2006-01-02"),
+		row.Point.ID,
+		num.FormatFloat(row.Point.LatLon[0]),
+		num.FormatFloat(row.Point.LatLon[1]),
+		strconv.Itoa(row.X),
+		strconv.Itoa(row.Y),
+		num.FormatFloat(row.Value),
+		formatTime(row.Time),
+	}
+	if cw.neighbourhood {
+		s := row.Neighbourhood
+		if s == nil {
+			s = &Summary{Mean: math.NaN(), Std: math.NaN(), Min: math.NaN(), Max: math.NaN()}
+		}
+		record = append(record, strconv.Itoa(s.Valid), num.FormatFloat(s.Mean), num.FormatFloat(s.Std), num.FormatFloat(s.Min), num.FormatFloat(s.Max))
+	}
+	return cw.w.Write(record)
+}
+
+func (cw *csvWriter) Flush() error {
+	cw.w.Flush()
+	return cw.w.Error()
+}
+
+type jsonlWriter struct {
+	w *bufio.Writer
+}
+
+// NewJSONLWriter returns a writer of JSON Lines, one object per row with NaN values and
+// missing times as null.
+func NewJSONLWriter(w io.Writer) RowWriter {
+	return &jsonlWriter{w: bufio.NewWriter(w)}
+}
+
+type jsonSummary struct {
+	Valid int           `json:"valid"`
+	Mean  num.JSONFloat `json:"mean"`
+	Std   num.JSONFloat `json:"std"`
+	Min   num.JSONFloat `json:"min"`
+	Max   num.JSONFloat `json:"max"`
+}
+
+func (jw *jsonlWriter) Write(row *Row) error {
+	doc := struct {
+		Dataset       string        `json:"dataset"`
+		Date          string        `json:"date"`
+		ID            string        `json:"id"`
+		Lat           num.JSONFloat `json:"lat"`
+		Lon           num.JSONFloat `json:"lon"`
+		X             int           `json:"x"`
+		Y             int           `json:"y"`
+		Value         num.JSONFloat `json:"value"`
+		Time          *time.Time    `json:"time"`
+		Neighbourhood *jsonSummary  `json:"neighbourhood,omitempty"`
+	}{
+		Dataset: row.Dataset,
+		Date:    row.Date.Format("2006-01-02"),
+		ID:      row.Point.ID,
+		Lat:     num.JSONFloat(row.Point.LatLon[0]),
+		Lon:     num.JSONFloat(row.Point.LatLon[1]),
+		X:       row.X,
+		Y:       row.Y,
+		Value:   num.JSONFloat(row.Value),
+	}
+	if !row.Time.IsZero() {
+		doc.Time = &row.Time
+	}
+	if s := row.Neighbourhood; s != nil {
+		doc.Neighbourhood = &jsonSummary{s.Valid, num.JSONFloat(s.Mean), num.JSONFloat(s.Std), num.JSONFloat(s.Min), num.JSONFloat(s.Max)}
+	}
+	data, err := json.Marshal(doc)
+	if err != nil {
+		return err
+	}
+	if _, err = jw.w.Write(append(data, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (jw *jsonlWriter) Flush() error {
+	return jw.w.Flush()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
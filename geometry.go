@@ -0,0 +1,36 @@
+package modis
+
+import "math"
+
+// Polygon defines a polygon by rings of lat/lon vertices in degrees: the first ring is the
+// exterior, further rings are holes. Rings may be given open or closed.
+type Polygon [][]LatLon
+
+// Contains tests whether the point lies within the exterior ring and outside of all holes,
+// treating latitude and longitude as planar coordinates.
+func (pg Polygon) Contains(ll LatLon) bool {
+	inside := false
+	for _, ring := range pg {
+		// even-odd rule: every ring crossed toggles the state
+		for i, j := 0, len(ring)-1; i < len(ring); j, i = i, i+1 {
+			a, b := ring[i], ring[j]
+			if (a[0] > ll[0]) != (b[0] > ll[0]) && ll[1] < (b[1]-a[1])*(ll[0]-a[0])/(b[0]-a[0])+a[1] {
+				inside = !inside
+			}
+		}
+	}
+	return inside
+}
+
+// Bounds returns the south-west and north-east corners of the bounding box of the exterior ring.
+func (pg Polygon) Bounds() (LatLon, LatLon) {
+	sw, ne := LatLon{math.Inf(1), math.Inf(1)}, LatLon{math.Inf(-1), math.Inf(-1)}
+	if len(pg) == 0 {
+		return sw, ne
+	}
+	for _, ll := range pg[0] {
+		sw = LatLon{math.Min(sw[0], ll[0]), math.Min(sw[1], ll[1])}
+		ne = LatLon{math.Max(ne[0], ll[0]), math.Max(ne[1], ll[1])}
+	}
+	return sw, ne
+}
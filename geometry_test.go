@@ -0,0 +1,33 @@
+package modis_test
+
+import (
+	"testing"
+
+	"github.com/nordicsense/modis"
+)
+
+func TestPolygon_Contains(t *testing.T) {
+	pg := modis.Polygon{
+		{{60, 20}, {60, 30}, {70, 30}, {70, 20}},
+		{{64, 24}, {64, 26}, {66, 26}, {66, 24}, {64, 24}},
+	}
+	cases := []struct {
+		ll       modis.LatLon
+		expected bool
+	}{
+		{modis.LatLon{62, 22}, true},
+		{modis.LatLon{65, 25}, false},
+		{modis.LatLon{67, 25}, true},
+		{modis.LatLon{71, 25}, false},
+		{modis.LatLon{65, 19}, false},
+	}
+	for _, c := range cases {
+		if actual := pg.Contains(c.ll); actual != c.expected {
+			t.Errorf("%v: expected %v, found %v", c.ll, c.expected, actual)
+		}
+	}
+	sw, ne := pg.Bounds()
+	if sw != (modis.LatLon{60, 20}) || ne != (modis.LatLon{70, 30}) {
+		t.Errorf("unexpected bounds %v, %v", sw, ne)
+	}
+}
@@ -0,0 +1,237 @@
+package hdfeos
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// HDF4 tags and constants used to locate global attributes, see the HDF4 specification.
+const (
+	hdf4Magic  = "\x0e\x03\x13\x01"
+	tagVH      = 1962 // vdata description
+	tagVS      = 1963 // vdata values
+	tagSpecial = 0x4000
+	attrClass  = "Attr0.0"
+	typeChar8  = 4
+	typeUChar8 = 3
+)
+
+// ReadFile reads the CoreMetadata, ArchiveMetadata and StructMetadata blocks from the global
+// attributes of an HDF4 file directly, independent of GDAL, which does not expose StructMetadata.
+// fileName may also be the name of an HDF-EOS subdataset, see ParseSubdataset.
+func ReadFile(fileName string) (*Metadata, error) {
+	if sub, ok := ParseSubdataset(fileName); ok {
+		fileName = sub.File
+	}
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	attrs, err := readAttributes(io.NewSectionReader(f, 0, fi.Size()), func(name string) bool {
+		name = strings.ToLower(name)
+		return strings.HasPrefix(name, "coremetadata.") || strings.HasPrefix(name, "archivemetadata.") ||
+			strings.HasPrefix(name, "structmetadata.")
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fileName, err)
+	}
+	return FromMetadata(attrs)
+}
+
+// Subdataset identifies a field of an HDF-EOS grid within a file.
+type Subdataset struct {
+	File  string
+	Grid  string
+	Field string
+}
+
+// ParseSubdataset parses GDAL subdataset names such as
+// HDF4_EOS:EOS_GRID:"MOD11A1.hdf":MODIS_Grid_Daily_1km_LST:LST_Day_1km.
+func ParseSubdataset(name string) (*Subdataset, bool) {
+	const prefix = "HDF4_EOS:EOS_GRID:"
+	if !strings.HasPrefix(name, prefix) {
+		return nil, false
+	}
+	start, end := strings.Index(name, `"`), strings.LastIndex(name, `"`)
+	if start < 0 || end <= start {
+		return nil, false
+	}
+	parts := strings.Split(name[end+1:], ":")
+	if len(parts) != 3 || parts[0] != "" {
+		return nil, false
+	}
+	return &Subdataset{File: name[start+1 : end], Grid: parts[1], Field: parts[2]}, true
+}
+
+type dd struct {
+	tag, ref       uint16
+	offset, length int32
+}
+
+// readAttributes reads the text-valued global attributes accepted by match, stored as vdata of
+// class Attr0.0, by name. Sizes read from the file are checked against its size before
+// allocating buffers for them.
+func readAttributes(r *io.SectionReader, match func(name string) bool) (map[string]string, error) {
+	magic := make([]byte, 4)
+	if _, err := r.ReadAt(magic, 0); err != nil || string(magic) != hdf4Magic {
+		return nil, fmt.Errorf("not an HDF4 file")
+	}
+	dds, err := readDDs(r)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[uint16]dd)
+	for _, d := range dds {
+		if d.tag == tagVS || d.tag == tagVS|tagSpecial {
+			values[d.ref] = d
+		}
+	}
+	res := make(map[string]string)
+	for _, d := range dds {
+		if d.tag != tagVH {
+			continue
+		}
+		buf, err := readElement(r, d)
+		if err != nil {
+			return nil, err
+		}
+		vh, err := parseVH(buf)
+		if err != nil {
+			return nil, fmt.Errorf("vdata %d: %v", d.ref, err)
+		}
+		if vh.class != attrClass || !match(vh.name) {
+			continue
+		}
+		if vh.fieldType != typeChar8 && vh.fieldType != typeUChar8 {
+			return nil, fmt.Errorf("attribute %s is not text", vh.name)
+		}
+		vs, ok := values[d.ref]
+		if !ok {
+			return nil, fmt.Errorf("no values of attribute %s", vh.name)
+		}
+		if vs.tag&tagSpecial != 0 {
+			return nil, fmt.Errorf("attribute %s is stored as a special (linked or compressed) element, which is not supported", vh.name)
+		}
+		data, err := readElement(r, vs)
+		if err != nil {
+			return nil, err
+		}
+		res[vh.name] = string(bytes.TrimRight(data, "\x00"))
+	}
+	return res, nil
+}
+
+// readDDs reads the data descriptors of all blocks following the file header.
+func readDDs(r *io.SectionReader) ([]dd, error) {
+	var res []dd
+	for offset, blocks := int64(4), 0; offset != 0; blocks++ {
+		if blocks > 1<<16 {
+			return nil, fmt.Errorf("too many descriptor blocks")
+		}
+		header := make([]byte, 6)
+		if _, err := r.ReadAt(header, offset); err != nil {
+			return nil, fmt.Errorf("descriptor block at %d: %v", offset, err)
+		}
+		n := int64(binary.BigEndian.Uint16(header))
+		next := int64(int32(binary.BigEndian.Uint32(header[2:])))
+		if offset+6+12*n > r.Size() {
+			return nil, fmt.Errorf("descriptor block at %d exceeds the file", offset)
+		}
+		buf := make([]byte, 12*n)
+		if _, err := r.ReadAt(buf, offset+6); err != nil {
+			return nil, fmt.Errorf("descriptor block at %d: %v", offset, err)
+		}
+		for i := int64(0); i < n; i++ {
+			b := buf[12*i:]
+			res = append(res, dd{
+				tag:    binary.BigEndian.Uint16(b),
+				ref:    binary.BigEndian.Uint16(b[2:]),
+				offset: int32(binary.BigEndian.Uint32(b[4:])),
+				length: int32(binary.BigEndian.Uint32(b[8:])),
+			})
+		}
+		offset = next
+	}
+	return res, nil
+}
+
+func readElement(r *io.SectionReader, d dd) ([]byte, error) {
+	if d.offset < 0 || d.length < 0 || int64(d.offset)+int64(d.length) > r.Size() {
+		return nil, fmt.Errorf("invalid element %d/%d", d.tag, d.ref)
+	}
+	buf := make([]byte, d.length)
+	if _, err := r.ReadAt(buf, int64(d.offset)); err != nil {
+		return nil, fmt.Errorf("element %d/%d: %v", d.tag, d.ref, err)
+	}
+	return buf, nil
+}
+
+// vdataHeader holds the parts of a vdata description needed to read single-field attributes.
+type vdataHeader struct {
+	name, class string
+	fieldType   uint16
+}
+
+// parseVH parses a vdata description: interlace, number of records, record size, number of
+// fields, the types, sizes, offsets and orders of the fields, field names, the vdata name and
+// class, all integers big-endian and strings prefixed by their 16 bit length.
+func parseVH(buf []byte) (*vdataHeader, error) {
+	rd := bytes.NewReader(buf)
+	var head struct {
+		Interlace uint16
+		Records   int32
+		Size      uint16
+		Fields    uint16
+	}
+	if err := binary.Read(rd, binary.BigEndian, &head); err != nil {
+		return nil, err
+	}
+	// types, sizes, offsets and orders
+	if 8*int(head.Fields) > rd.Len() {
+		return nil, fmt.Errorf("%d fields exceed the description", head.Fields)
+	}
+	ints := make([]uint16, 4*int(head.Fields))
+	if err := binary.Read(rd, binary.BigEndian, ints); err != nil {
+		return nil, err
+	}
+	readString := func() (string, error) {
+		var n uint16
+		if err := binary.Read(rd, binary.BigEndian, &n); err != nil {
+			return "", err
+		}
+		if int(n) > rd.Len() {
+			return "", fmt.Errorf("string of %d bytes exceeds the description", n)
+		}
+		s := make([]byte, n)
+		if _, err := io.ReadFull(rd, s); err != nil {
+			return "", err
+		}
+		return string(s), nil
+	}
+	for i := 0; i < int(head.Fields); i++ {
+		if _, err := readString(); err != nil {
+			return nil, err
+		}
+	}
+	res := &vdataHeader{}
+	var err error
+	if res.name, err = readString(); err != nil {
+		return nil, err
+	}
+	if res.class, err = readString(); err != nil {
+		return nil, err
+	}
+	if head.Fields > 0 {
+		res.fieldType = ints[0]
+	}
+	return res, nil
+}
@@ -0,0 +1,160 @@
+package hdfeos_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/nordicsense/modis/hdfeos"
+)
+
+type vdata struct {
+	name, class, value string
+}
+
+// writeHDF4 writes a minimal HDF4 file holding each vdata as a single char8 field, laid out as by
+// the HDF4 library for global attributes: one descriptor block followed by the elements.
+func writeHDF4(t *testing.T, fileName string, vdatas []vdata) {
+	put := func(w *bytes.Buffer, values ...interface{}) {
+		for _, v := range values {
+			if err := binary.Write(w, binary.BigEndian, v); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	var elements bytes.Buffer
+	type element struct {
+		tag, ref uint16
+		data     []byte
+	}
+	var els []element
+	for i, v := range vdatas {
+		var vh bytes.Buffer
+		str := func(s string) {
+			put(&vh, uint16(len(s)))
+			vh.WriteString(s)
+		}
+		// interlace, records, record size, fields, type, size, offset and order of the field
+		put(&vh, uint16(0), int32(1), uint16(len(v.value)), uint16(1),
+			uint16(4), uint16(len(v.value)), uint16(0), uint16(len(v.value)))
+		str("VALUES")
+		str(v.name)
+		str(v.class)
+		// extension tag and ref, version and unused
+		put(&vh, []uint16{0, 0, 3, 0})
+		ref := uint16(i + 2)
+		els = append(els, element{1962, ref, vh.Bytes()}, element{1963, ref, []byte(v.value + "\x00")})
+	}
+	// an unused descriptor as written for preallocated blocks
+	n := len(els) + 1
+	start := 4 + 6 + 12*n
+	var dds bytes.Buffer
+	put(&dds, uint16(n), int32(0))
+	for _, e := range els {
+		put(&dds, e.tag, e.ref, int32(start+elements.Len()), int32(len(e.data)))
+		elements.Write(e.data)
+	}
+	put(&dds, uint16(1), uint16(0), int32(-1), int32(-1))
+	data := append([]byte("\x0e\x03\x13\x01"), dds.Bytes()...)
+	if err := ioutil.WriteFile(fileName, append(data, elements.Bytes()...), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestReadFile(t *testing.T) {
+	dir := t.TempDir()
+	fileName := path.Join(dir, "MOD11A1.A2020183.h19v02.061.hdf")
+	half := len(structMetadata) / 2
+	writeHDF4(t, fileName, []vdata{
+		{"CoreMetadata.0", "Attr0.0", coreMetadata},
+		{"StructMetadata.0", "Attr0.0", structMetadata[:half]},
+		{"StructMetadata.1", "Attr0.0", structMetadata[half:]},
+		{"HDFEOSVersion", "Attr0.0", "HDFEOS_V2.19"},
+		{"StructMetadata.0", "Data0.0", "not an attribute"},
+	})
+	md, err := hdfeos.ReadFile(`HDF4_EOS:EOS_GRID:"` + fileName + `":MODIS_Grid_Daily_1km_LST:LST_Day_1km`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if md.Archive != nil {
+		t.Error("expected no ArchiveMetadata")
+	}
+	if id, err := md.GranuleID(); err != nil || id != "MOD11A1.A2020183.h19v02.061.2021014085632.hdf" {
+		t.Errorf("unexpected granule id %s (%v)", id, err)
+	}
+	if grid, err := md.Grid("MODIS_Grid_Daily_1km_LST"); err != nil || grid.XDim != 1200 {
+		t.Errorf("unexpected grid %+v (%v)", grid, err)
+	}
+
+	notHDF := path.Join(dir, "plain.tif")
+	_ = ioutil.WriteFile(notHDF, []byte("II*\x00"), 0644)
+	if _, err = hdfeos.ReadFile(notHDF); err == nil {
+		t.Error("expected error for a file that is not HDF4")
+	}
+	if _, err = hdfeos.ReadFile(path.Join(dir, "missing.hdf")); !os.IsNotExist(err) {
+		t.Errorf("expected missing file error, found %v", err)
+	}
+}
+
+func TestReadFile_Corrupt(t *testing.T) {
+	dir := t.TempDir()
+	fileName := path.Join(dir, "valid.hdf")
+	writeHDF4(t, fileName, []vdata{{"CoreMetadata.0", "Attr0.0", coreMetadata}})
+	valid, err := ioutil.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the first descriptor follows the magic and the block header, the vdata description it
+	// points to starts with the interlace, number of records and record size
+	const dd = 4 + 6
+	vh := int(binary.BigEndian.Uint32(valid[dd+4:]))
+	for name, patch := range map[string]func(data []byte){
+		"element length": func(data []byte) { binary.BigEndian.PutUint32(data[dd+8:], 0x7fffffff) },
+		"field count":    func(data []byte) { binary.BigEndian.PutUint16(data[vh+8:], 0xffff) },
+	} {
+		data := append([]byte(nil), valid...)
+		patch(data)
+		corrupt := path.Join(dir, "corrupt.hdf")
+		if err = ioutil.WriteFile(corrupt, data, 0644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err = hdfeos.ReadFile(corrupt); err == nil {
+			t.Errorf("%s: expected error for a corrupt file", name)
+		}
+	}
+}
+
+// Set MODIS_HDF_FIXTURE to an HDF-EOS granule, e.g. a MOD11A1 file, to read real metadata.
+func TestReadFile_Granule(t *testing.T) {
+	fileName := os.Getenv("MODIS_HDF_FIXTURE")
+	if fileName == "" {
+		t.Skip("MODIS_HDF_FIXTURE not set")
+	}
+	md, err := hdfeos.ReadFile(fileName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = md.GranuleID(); err != nil {
+		t.Error(err)
+	}
+	grids, err := md.Grids()
+	if err != nil || len(grids) == 0 {
+		t.Fatalf("expected grids in StructMetadata, found %v (%v)", grids, err)
+	}
+	if _, err = grids[0].Transform(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestParseSubdataset(t *testing.T) {
+	sub, ok := hdfeos.ParseSubdataset(`HDF4_EOS:EOS_GRID:"/data/MOD11A1.hdf":MODIS_Grid_Daily_1km_LST:QC_Day`)
+	if !ok || *sub != (hdfeos.Subdataset{File: "/data/MOD11A1.hdf", Grid: "MODIS_Grid_Daily_1km_LST", Field: "QC_Day"}) {
+		t.Errorf("unexpected subdataset %+v", sub)
+	}
+	if _, ok = hdfeos.ParseSubdataset("/data/MOD11A1.hdf"); ok {
+		t.Error("expected no subdataset for a file name")
+	}
+}
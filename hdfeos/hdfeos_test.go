@@ -0,0 +1,223 @@
+package hdfeos_test
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/hdfeos"
+)
+
+const structMetadata = `GROUP=SwathStructure
+END_GROUP=SwathStructure
+GROUP=GridStructure
+	GROUP=GRID_1
+		GridName="MODIS_Grid_Daily_1km_LST"
+		XDim=1200
+		YDim=1200
+		UpperLeftPointMtrs=(0.000000,7783653.637667)
+		LowerRightMtrs=(1111950.519667,6671703.118000)
+		Projection=GCTP_SNSOID
+		ProjParams=(6371007.181000,0,0,0,0,0,0,0,0,0,0,0,0)
+		SphereCode=-1
+		GridOrigin=HDFE_GD_UL
+		GROUP=Dimension
+		END_GROUP=Dimension
+		GROUP=DataField
+			OBJECT=DataField_1
+				DataFieldName="LST_Day_1km"
+				DataType=DFNT_UINT16
+				DimList=("YDim","XDim")
+			END_OBJECT=DataField_1
+			OBJECT=DataField_2
+				DataFieldName="QC_Day"
+				DataType=DFNT_UINT8
+				DimList=("YDim","XDim")
+			END_OBJECT=DataField_2
+		END_GROUP=DataField
+	END_GROUP=GRID_1
+END_GROUP=GridStructure
+END
+`
+
+const coreMetadata = `
+GROUP                  = INVENTORYMETADATA
+  GROUPTYPE            = MASTERGROUP
+
+  GROUP                  = ECSDATAGRANULE
+
+    OBJECT                 = LOCALGRANULEID
+      NUM_VAL              = 1
+      VALUE                = "MOD11A1.A2020183.h19v02.061.2021014085632.hdf"
+    END_OBJECT             = LOCALGRANULEID
+
+    OBJECT                 = DAYNIGHTFLAG
+      NUM_VAL              = 1
+      VALUE                = "Both"
+    END_OBJECT             = DAYNIGHTFLAG
+
+  END_GROUP              = ECSDATAGRANULE
+
+  /* measured parameters */
+  GROUP                  = MEASUREDPARAMETER
+
+    OBJECT                 = MEASUREDPARAMETERCONTAINER
+      CLASS                = "1"
+
+      GROUP                  = QASTATS
+        CLASS                = "1"
+
+        OBJECT                 = QAPERCENTCLOUDCOVER
+          NUM_VAL              = 1
+          CLASS                = "1"
+          VALUE                = 25
+        END_OBJECT             = QAPERCENTCLOUDCOVER
+
+      END_GROUP              = QASTATS
+
+    END_OBJECT             = MEASUREDPARAMETERCONTAINER
+
+  END_GROUP              = MEASUREDPARAMETER
+
+END_GROUP              = INVENTORYMETADATA
+
+END
+`
+
+const archiveMetadata = `GROUP = ARCHIVEDMETADATA
+  GROUP = BOUNDINGRECTANGLE
+    OBJECT = NORTHBOUNDINGCOORDINATE
+      NUM_VAL = 1
+      VALUE = 70.0
+    END_OBJECT = NORTHBOUNDINGCOORDINATE
+    OBJECT = SOUTHBOUNDINGCOORDINATE
+      NUM_VAL = 1
+      VALUE = 60.0
+    END_OBJECT = SOUTHBOUNDINGCOORDINATE
+    OBJECT = EASTBOUNDINGCOORDINATE
+      NUM_VAL = 1
+      VALUE = 29.2380059331161
+    END_OBJECT = EASTBOUNDINGCOORDINATE
+    OBJECT = WESTBOUNDINGCOORDINATE
+      NUM_VAL = 1
+      VALUE = 0.0
+    END_OBJECT = WESTBOUNDINGCOORDINATE
+  END_GROUP = BOUNDINGRECTANGLE
+  CHARACTERISTICBINSIZE = {926.625433055556,
+    926.625433055556}
+END_GROUP = ARCHIVEDMETADATA
+END
+`
+
+func TestParseODL(t *testing.T) {
+	root, err := hdfeos.ParseODL(archiveMetadata)
+	if err != nil {
+		t.Fatal(err)
+	}
+	am := root.Child("ArchivedMetadata")
+	if am == nil || len(am.Children) != 1 {
+		t.Fatalf("unexpected root %+v", root)
+	}
+	if v, _ := am.Value("CHARACTERISTICBINSIZE"); !reflect.DeepEqual(v, []interface{}{926.625433055556, 926.625433055556}) {
+		t.Errorf("unexpected set %v", v)
+	}
+	north := am.Find("NORTHBOUNDINGCOORDINATE")
+	if north == nil || !north.Object {
+		t.Fatal("expected object")
+	}
+	if v, err := north.Int("NUM_VAL"); err != nil || v != 1 {
+		t.Errorf("unexpected NUM_VAL %v (%v)", v, err)
+	}
+	if _, err = north.String("VALUE"); err == nil {
+		t.Error("expected error for number as string")
+	}
+
+	for _, text := range []string{
+		"GROUP = A\nEND_OBJECT = A\n",
+		"GROUP = A\nEND_GROUP = B\n",
+		"GROUP = A\n",
+		"KEY = (1, 2\n",
+		`KEY = "unterminated`,
+	} {
+		if _, err = hdfeos.ParseODL(text); err == nil {
+			t.Errorf("expected error for %q", text)
+		}
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	half := len(structMetadata) / 2
+	md, err := hdfeos.FromMetadata(map[string]string{
+		"CoreMetadata.0":     coreMetadata,
+		"ArchiveMetadata.0":  archiveMetadata,
+		"StructMetadata.1":   structMetadata[half:],
+		"StructMetadata.0":   structMetadata[:half],
+		"RANGEBEGINNINGDATE": "2020-07-01",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, err := md.GranuleID(); err != nil || !strings.HasPrefix(id, "MOD11A1.A2020183") {
+		t.Errorf("unexpected granule id %s (%v)", id, err)
+	}
+	if flag, err := md.DayNightFlag(); err != nil || flag != "Both" {
+		t.Errorf("unexpected day/night flag %s (%v)", flag, err)
+	}
+	if cc, err := md.CloudCover(); err != nil || cc != 25 {
+		t.Errorf("unexpected cloud cover %v (%v)", cc, err)
+	}
+	sw, ne, err := md.BoundingRectangle()
+	if err != nil || sw != (modis.LatLon{60, 0}) || ne != (modis.LatLon{70, 29.2380059331161}) {
+		t.Errorf("unexpected bounding rectangle %v %v (%v)", sw, ne, err)
+	}
+	grid, err := md.Grid("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := &hdfeos.Grid{
+		Name:       "MODIS_Grid_Daily_1km_LST",
+		XDim:       1200,
+		YDim:       1200,
+		UpperLeft:  [2]float64{0, 7783653.637667},
+		LowerRight: [2]float64{1111950.519667, 6671703.118},
+		Projection: "GCTP_SNSOID",
+		ProjParams: []float64{6371007.181, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+		SphereCode: -1,
+		Origin:     "HDFE_GD_UL",
+		Fields:     []string{"LST_Day_1km", "QC_Day"},
+	}
+	if !reflect.DeepEqual(grid, expected) {
+		t.Errorf("expected %+v, found %+v", expected, grid)
+	}
+	if _, err = md.Grid("MODIS_Grid_8Day_1km_LST"); err == nil {
+		t.Error("expected error for unknown grid")
+	}
+
+	md, err = hdfeos.FromMetadata(map[string]string{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = md.GranuleID(); err == nil {
+		t.Error("expected error for missing CoreMetadata")
+	}
+	if _, err = md.Grids(); err == nil {
+		t.Error("expected error for missing StructMetadata")
+	}
+}
+
+func TestGrid_Transform(t *testing.T) {
+	g := &hdfeos.Grid{Name: "CMG", XDim: 7200, YDim: 3600, Projection: "GCTP_GEO",
+		UpperLeft: [2]float64{-180000000, 90000000}, LowerRight: [2]float64{180000000, -90000000}}
+	at, err := g.Transform()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if at != (modis.AffineTransform{-180, 0.05, 0, 90, 0, -0.05}) {
+		t.Errorf("unexpected transform %v", at)
+	}
+	g.Projection, g.UpperLeft, g.LowerRight = "GCTP_SNSOID", [2]float64{-100, 100}, [2]float64{620, -260}
+	if at, _ = g.Transform(); at != (modis.AffineTransform{-100, 0.1, 0, 100, 0, -0.1}) {
+		t.Errorf("unexpected transform %v", at)
+	}
+}
@@ -0,0 +1,254 @@
+package hdfeos
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/nordicsense/modis"
+)
+
+// Metadata holds the parsed ODL blocks of a granule, blocks that were not present are nil.
+type Metadata struct {
+	Core    *Group
+	Archive *Group
+	Struct  *Group
+}
+
+// Grid is the definition of an HDF-EOS grid from StructMetadata.
+type Grid struct {
+	Name       string
+	XDim       int
+	YDim       int
+	UpperLeft  [2]float64
+	LowerRight [2]float64
+	Projection string
+	ProjParams []float64
+	SphereCode int
+	Origin     string
+	Fields     []string
+}
+
+// FromMetadata parses the CoreMetadata, ArchiveMetadata and StructMetadata blocks found in
+// metadata items keyed by block name. Blocks split into parts (CoreMetadata.0, CoreMetadata.1,
+// ...) are joined before parsing. GDAL does not list these blocks for HDF4 files, use ReadFile
+// to read them from the file.
+func FromMetadata(md map[string]string) (*Metadata, error) {
+	res := &Metadata{}
+	for _, block := range []struct {
+		name string
+		dst  **Group
+	}{{"CoreMetadata", &res.Core}, {"ArchiveMetadata", &res.Archive}, {"StructMetadata", &res.Struct}} {
+		text := joinParts(md, block.name)
+		if text == "" {
+			continue
+		}
+		g, err := ParseODL(text)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", block.name, err)
+		}
+		*block.dst = g
+	}
+	return res, nil
+}
+
+func joinParts(md map[string]string, name string) string {
+	parts := make(map[int]string)
+	var indices []int
+	for k, v := range md {
+		if !strings.HasPrefix(strings.ToLower(k), strings.ToLower(name)+".") {
+			continue
+		}
+		if i, err := strconv.Atoi(k[len(name)+1:]); err == nil {
+			parts[i] = v
+			indices = append(indices, i)
+		}
+	}
+	sort.Ints(indices)
+	var sb strings.Builder
+	for _, i := range indices {
+		sb.WriteString(parts[i])
+	}
+	return sb.String()
+}
+
+// GranuleID returns LOCALGRANULEID of CoreMetadata.
+func (m *Metadata) GranuleID() (string, error) {
+	return m.coreValue("LOCALGRANULEID")
+}
+
+// DayNightFlag returns DAYNIGHTFLAG of CoreMetadata, one of Day, Night or Both.
+func (m *Metadata) DayNightFlag() (string, error) {
+	return m.coreValue("DAYNIGHTFLAG")
+}
+
+// CloudCover returns QAPERCENTCLOUDCOVER of the first measured parameter in CoreMetadata.
+func (m *Metadata) CloudCover() (float64, error) {
+	o, err := m.coreObject("QAPERCENTCLOUDCOVER")
+	if err != nil {
+		return 0, err
+	}
+	return o.Float("VALUE")
+}
+
+// BoundingRectangle returns the south-west and north-east corners of the BOUNDINGRECTANGLE
+// of ArchiveMetadata or, if missing there, of CoreMetadata.
+func (m *Metadata) BoundingRectangle() (sw, ne modis.LatLon, err error) {
+	var rect *Group
+	for _, g := range []*Group{m.Archive, m.Core} {
+		if g != nil {
+			if rect = g.Find("BOUNDINGRECTANGLE"); rect != nil {
+				break
+			}
+		}
+	}
+	if rect == nil {
+		return sw, ne, fmt.Errorf("no BOUNDINGRECTANGLE in metadata")
+	}
+	var values [4]float64
+	for i, name := range []string{"SOUTHBOUNDINGCOORDINATE", "WESTBOUNDINGCOORDINATE", "NORTHBOUNDINGCOORDINATE", "EASTBOUNDINGCOORDINATE"} {
+		o := rect.Child(name)
+		if o == nil {
+			return sw, ne, fmt.Errorf("no %s in BOUNDINGRECTANGLE", name)
+		}
+		if values[i], err = o.Float("VALUE"); err != nil {
+			return sw, ne, err
+		}
+	}
+	return modis.LatLon{values[0], values[1]}, modis.LatLon{values[2], values[3]}, nil
+}
+
+// Grids returns the grid definitions of StructMetadata.
+func (m *Metadata) Grids() ([]*Grid, error) {
+	if m.Struct == nil {
+		return nil, fmt.Errorf("no StructMetadata")
+	}
+	gs := m.Struct.Child("GridStructure")
+	if gs == nil {
+		return nil, fmt.Errorf("no GridStructure in StructMetadata")
+	}
+	var res []*Grid
+	for _, g := range gs.Children {
+		grid, err := parseGrid(g)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, grid)
+	}
+	return res, nil
+}
+
+// Grid returns the grid definition of given name, or the only grid if name is empty.
+func (m *Metadata) Grid(name string) (*Grid, error) {
+	grids, err := m.Grids()
+	if err != nil {
+		return nil, err
+	}
+	if name == "" && len(grids) == 1 {
+		return grids[0], nil
+	}
+	for _, g := range grids {
+		if g.Name == name {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("no grid %s in StructMetadata", name)
+}
+
+func parseGrid(g *Group) (*Grid, error) {
+	res := &Grid{}
+	var err error
+	if res.Name, err = g.String("GridName"); err != nil {
+		return nil, err
+	}
+	for _, dim := range []struct {
+		key string
+		dst *int
+	}{{"XDim", &res.XDim}, {"YDim", &res.YDim}} {
+		v, err := g.Int(dim.key)
+		if err != nil {
+			return nil, err
+		}
+		*dim.dst = int(v)
+	}
+	for _, corner := range []struct {
+		key string
+		dst *[2]float64
+	}{{"UpperLeftPointMtrs", &res.UpperLeft}, {"LowerRightMtrs", &res.LowerRight}} {
+		values, err := g.Floats(corner.key)
+		if err != nil {
+			return nil, err
+		}
+		if len(values) != 2 {
+			return nil, fmt.Errorf("%s of grid %s is not a pair: %v", corner.key, res.Name, values)
+		}
+		copy(corner.dst[:], values)
+	}
+	if res.Projection, err = g.String("Projection"); err != nil {
+		return nil, err
+	}
+	// optional attributes
+	res.ProjParams, _ = g.Floats("ProjParams")
+	if sc, err := g.Int("SphereCode"); err == nil {
+		res.SphereCode = int(sc)
+	}
+	res.Origin, _ = g.String("GridOrigin")
+	if fields := g.Child("DataField"); fields != nil {
+		for _, f := range fields.Children {
+			if name, err := f.String("DataFieldName"); err == nil {
+				res.Fields = append(res.Fields, name)
+			}
+		}
+	}
+	return res, nil
+}
+
+func (m *Metadata) coreObject(name string) (*Group, error) {
+	if m.Core == nil {
+		return nil, fmt.Errorf("no CoreMetadata")
+	}
+	o := m.Core.Find(name)
+	if o == nil {
+		return nil, fmt.Errorf("no %s in CoreMetadata", name)
+	}
+	return o, nil
+}
+
+func (m *Metadata) coreValue(name string) (string, error) {
+	o, err := m.coreObject(name)
+	if err != nil {
+		return "", err
+	}
+	return o.String("VALUE")
+}
+
+// Transform returns the affine transform of the grid from its corners and dimensions. Corners
+// of geographic grids (GCTP_GEO) are converted from packed DMS (DDDMMMSSS.SS) to degrees.
+func (g *Grid) Transform() (modis.AffineTransform, error) {
+	if g.XDim <= 0 || g.YDim <= 0 {
+		return modis.AffineTransform{}, fmt.Errorf("invalid dimensions %dx%d of grid %s", g.XDim, g.YDim, g.Name)
+	}
+	ul, lr := g.UpperLeft, g.LowerRight
+	if g.Projection == "GCTP_GEO" {
+		for i := range ul {
+			ul[i], lr[i] = packedDMS2Degrees(ul[i]), packedDMS2Degrees(lr[i])
+		}
+	}
+	return modis.AffineTransform{
+		ul[0], (lr[0] - ul[0]) / float64(g.XDim), 0,
+		ul[1], 0, (lr[1] - ul[1]) / float64(g.YDim),
+	}, nil
+}
+
+func packedDMS2Degrees(v float64) float64 {
+	sign := 1.0
+	if v < 0 {
+		sign, v = -1, -v
+	}
+	deg := math.Floor(v / 1e6)
+	min := math.Floor((v - deg*1e6) / 1e3)
+	sec := v - deg*1e6 - min*1e3
+	return sign * (deg + min/60 + sec/3600)
+}
@@ -0,0 +1,323 @@
+// Package hdfeos parses the ODL (Object Description Language) metadata of HDF-EOS files, i.e.
+// the CoreMetadata, ArchiveMetadata and StructMetadata blocks, and provides accessors for
+// commonly used granule and grid properties.
+package hdfeos
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Symbol is an unquoted ODL value such as HDFE_GD_UL or GCTP_SNSOID.
+type Symbol string
+
+// Attr is a key-value pair of a group. Values are string (quoted), Symbol, int64, float64
+// or []interface{} of those for tuples and sets.
+type Attr struct {
+	Key   string
+	Value interface{}
+}
+
+// Group is an ODL GROUP or OBJECT with its attributes and nested groups and objects.
+// The root group returned by ParseODL has no name.
+type Group struct {
+	Name     string
+	Object   bool
+	Attrs    []Attr
+	Children []*Group
+}
+
+// ParseODL parses ODL text into its root group.
+func ParseODL(text string) (*Group, error) {
+	l := &lexer{src: text, line: 1}
+	root := &Group{}
+	stack := []*Group{root}
+	for {
+		tok, err := l.next()
+		if err != nil {
+			return nil, err
+		}
+		if tok.kind == tokEOF {
+			break
+		}
+		if tok.kind != tokWord {
+			return nil, fmt.Errorf("line %d: expected a key, found %q", tok.line, tok.text)
+		}
+		key := strings.ToUpper(tok.text)
+		if key == "END" {
+			break
+		}
+		var value interface{}
+		if eq, err := l.peek(); err != nil {
+			return nil, err
+		} else if eq.kind == tokPunct && eq.text == "=" {
+			_, _ = l.next()
+			if value, err = l.value(); err != nil {
+				return nil, err
+			}
+		} else if key != "END_GROUP" && key != "END_OBJECT" {
+			return nil, fmt.Errorf("line %d: expected = after %s", tok.line, tok.text)
+		}
+		current := stack[len(stack)-1]
+		switch key {
+		case "GROUP", "OBJECT":
+			child := &Group{Name: fmt.Sprint(value), Object: key == "OBJECT"}
+			current.Children = append(current.Children, child)
+			stack = append(stack, child)
+		case "END_GROUP", "END_OBJECT":
+			if len(stack) == 1 || current.Object != (key == "END_OBJECT") {
+				return nil, fmt.Errorf("line %d: unexpected %s", tok.line, tok.text)
+			}
+			if value != nil && fmt.Sprint(value) != current.Name {
+				return nil, fmt.Errorf("line %d: %s = %v closes %s", tok.line, tok.text, value, current.Name)
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			current.Attrs = append(current.Attrs, Attr{Key: tok.text, Value: value})
+		}
+	}
+	if len(stack) > 1 {
+		return nil, fmt.Errorf("unterminated group %s", stack[len(stack)-1].Name)
+	}
+	return root, nil
+}
+
+// Value returns the value of an attribute by case-insensitive key.
+func (g *Group) Value(key string) (interface{}, bool) {
+	for _, a := range g.Attrs {
+		if strings.EqualFold(a.Key, key) {
+			return a.Value, true
+		}
+	}
+	return nil, false
+}
+
+// String returns a string or symbol attribute.
+func (g *Group) String(key string) (string, error) {
+	v, ok := g.Value(key)
+	if !ok {
+		return "", fmt.Errorf("no attribute %s in %s", key, g.Name)
+	}
+	switch s := v.(type) {
+	case string:
+		return s, nil
+	case Symbol:
+		return string(s), nil
+	}
+	return "", fmt.Errorf("attribute %s of %s is not a string: %v", key, g.Name, v)
+}
+
+// Float returns a numeric attribute.
+func (g *Group) Float(key string) (float64, error) {
+	v, ok := g.Value(key)
+	if !ok {
+		return 0, fmt.Errorf("no attribute %s in %s", key, g.Name)
+	}
+	if f, ok := toFloat(v); ok {
+		return f, nil
+	}
+	return 0, fmt.Errorf("attribute %s of %s is not a number: %v", key, g.Name, v)
+}
+
+// Int returns an integer attribute.
+func (g *Group) Int(key string) (int64, error) {
+	v, ok := g.Value(key)
+	if !ok {
+		return 0, fmt.Errorf("no attribute %s in %s", key, g.Name)
+	}
+	if i, ok := v.(int64); ok {
+		return i, nil
+	}
+	return 0, fmt.Errorf("attribute %s of %s is not an integer: %v", key, g.Name, v)
+}
+
+// Floats returns a numeric tuple or set attribute, a single number is returned as one value.
+func (g *Group) Floats(key string) ([]float64, error) {
+	v, ok := g.Value(key)
+	if !ok {
+		return nil, fmt.Errorf("no attribute %s in %s", key, g.Name)
+	}
+	values, ok := v.([]interface{})
+	if !ok {
+		values = []interface{}{v}
+	}
+	var res []float64
+	for _, value := range values {
+		f, ok := toFloat(value)
+		if !ok {
+			return nil, fmt.Errorf("attribute %s of %s is not numeric: %v", key, g.Name, v)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// Child returns the direct child group or object of given case-insensitive name.
+func (g *Group) Child(name string) *Group {
+	for _, c := range g.Children {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// Find returns the first group or object of given case-insensitive name in depth-first
+// order below g, nil if there is none.
+func (g *Group) Find(name string) *Group {
+	for _, c := range g.Children {
+		if strings.EqualFold(c.Name, name) {
+			return c
+		}
+		if res := c.Find(name); res != nil {
+			return res
+		}
+	}
+	return nil
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+const (
+	tokEOF = iota
+	tokWord
+	tokString
+	tokPunct
+)
+
+type token struct {
+	kind int
+	text string
+	line int
+}
+
+type lexer struct {
+	src    string
+	pos    int
+	line   int
+	peeked *token
+}
+
+func (l *lexer) peek() (token, error) {
+	if l.peeked == nil {
+		tok, err := l.scan()
+		if err != nil {
+			return tok, err
+		}
+		l.peeked = &tok
+	}
+	return *l.peeked, nil
+}
+
+func (l *lexer) next() (token, error) {
+	tok, err := l.peek()
+	l.peeked = nil
+	return tok, err
+}
+
+func (l *lexer) scan() (token, error) {
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		if c == '\n' {
+			l.line++
+		}
+		if unicode.IsSpace(rune(c)) || c == 0 {
+			l.pos++
+		} else if strings.HasPrefix(l.src[l.pos:], "/*") {
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				return token{}, fmt.Errorf("line %d: unterminated comment", l.line)
+			}
+			l.line += strings.Count(l.src[l.pos:l.pos+2+end], "\n")
+			l.pos += end + 4
+		} else {
+			break
+		}
+	}
+	if l.pos >= len(l.src) {
+		return token{kind: tokEOF, line: l.line}, nil
+	}
+	start, c := l.pos, l.src[l.pos]
+	switch {
+	case c == '"':
+		end := strings.IndexByte(l.src[start+1:], '"')
+		if end < 0 {
+			return token{}, fmt.Errorf("line %d: unterminated string", l.line)
+		}
+		text := l.src[start+1 : start+1+end]
+		l.pos = start + end + 2
+		tok := token{kind: tokString, text: text, line: l.line}
+		l.line += strings.Count(text, "\n")
+		return tok, nil
+	case strings.IndexByte("=(){},", c) >= 0:
+		l.pos++
+		return token{kind: tokPunct, text: string(c), line: l.line}, nil
+	}
+	for l.pos < len(l.src) && !unicode.IsSpace(rune(l.src[l.pos])) && strings.IndexByte("=(){},\"", l.src[l.pos]) < 0 {
+		l.pos++
+	}
+	return token{kind: tokWord, text: l.src[start:l.pos], line: l.line}, nil
+}
+
+// value parses a single value, tuple (a, b) or set {a, b}.
+func (l *lexer) value() (interface{}, error) {
+	tok, err := l.next()
+	if err != nil {
+		return nil, err
+	}
+	switch tok.kind {
+	case tokString:
+		return tok.text, nil
+	case tokWord:
+		if i, err := strconv.ParseInt(tok.text, 10, 64); err == nil {
+			return i, nil
+		}
+		if f, err := strconv.ParseFloat(tok.text, 64); err == nil {
+			return f, nil
+		}
+		return Symbol(tok.text), nil
+	case tokPunct:
+		if tok.text != "(" && tok.text != "{" {
+			break
+		}
+		closing := ")"
+		if tok.text == "{" {
+			closing = "}"
+		}
+		var res []interface{}
+		for {
+			if next, err := l.peek(); err != nil {
+				return nil, err
+			} else if next.kind == tokPunct && next.text == closing {
+				_, _ = l.next()
+				return res, nil
+			}
+			v, err := l.value()
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, v)
+			sep, err := l.next()
+			if err != nil {
+				return nil, err
+			}
+			if sep.kind == tokPunct && sep.text == closing {
+				return res, nil
+			}
+			if sep.kind != tokPunct || sep.text != "," {
+				return nil, fmt.Errorf("line %d: expected , or %s, found %q", sep.line, closing, sep.text)
+			}
+		}
+	}
+	return nil, fmt.Errorf("line %d: unexpected %q", tok.line, tok.text)
+}
@@ -2,6 +2,7 @@ package modis
 
 import (
 	"math"
+	"sort"
 	"time"
 
 	"github.com/nordicsense/gdal"
@@ -17,30 +18,64 @@ type ImageParams struct {
 	offset     float64
 	scale      float64
 	datatype   gdal.DataType
-	metadata   map[string]string
-	date       time.Time
+	// metadata and bandMetadata map domains to items, "" being the default domain
+	metadata     map[string]map[string]string
+	bandMetadata map[string]map[string]string
+	date         time.Time
 }
 
 func (ip *ImageParams) copy() *ImageParams {
 	res := &ImageParams{
-		xSize:      ip.xSize,
-		ySize:      ip.ySize,
-		transform:  ip.transform,
-		projection: ip.projection,
-		nan:        ip.nan,
-		nanPresent: ip.nanPresent,
-		offset:     ip.offset,
-		scale:      ip.scale,
-		datatype:   ip.datatype,
-		metadata:   make(map[string]string),
-		date:       ip.date,
+		xSize:        ip.xSize,
+		ySize:        ip.ySize,
+		transform:    ip.transform,
+		projection:   ip.projection,
+		nan:          ip.nan,
+		nanPresent:   ip.nanPresent,
+		offset:       ip.offset,
+		scale:        ip.scale,
+		datatype:     ip.datatype,
+		metadata:     copyDomains(ip.metadata),
+		bandMetadata: copyDomains(ip.bandMetadata),
+		date:         ip.date,
 	}
-	for k, v := range ip.metadata {
-		res.metadata[k] = v
+	return res
+}
+
+func copyDomains(domains map[string]map[string]string) map[string]map[string]string {
+	res := make(map[string]map[string]string, len(domains))
+	for domain, items := range domains {
+		res[domain] = copyItems(items)
+	}
+	return res
+}
+
+func copyItems(items map[string]string) map[string]string {
+	res := make(map[string]string, len(items))
+	for k, v := range items {
+		res[k] = v
+	}
+	return res
+}
+
+func domainNames(domains map[string]map[string]string) []string {
+	var res []string
+	for domain, items := range domains {
+		if len(items) > 0 {
+			res = append(res, domain)
+		}
 	}
+	sort.Strings(res)
 	return res
 }
 
+func setItem(domains map[string]map[string]string, domain, key, value string) {
+	if domains[domain] == nil {
+		domains[domain] = make(map[string]string)
+	}
+	domains[domain][key] = value
+}
+
 func (ip *ImageParams) ToBuilder() *imageParamsBuilder {
 	return &imageParamsBuilder{ImageParams: ip.copy()}
 }
@@ -81,8 +116,34 @@ func (ip *ImageParams) Date() time.Time {
 	return ip.date
 }
 
+// Metadata returns a copy of the dataset metadata of the default domain.
 func (ip *ImageParams) Metadata() map[string]string {
-	return ip.metadata // TODO: copy or protect
+	return copyItems(ip.metadata[""])
+}
+
+// DomainMetadata returns a copy of the dataset metadata of a domain.
+func (ip *ImageParams) DomainMetadata(domain string) map[string]string {
+	return copyItems(ip.metadata[domain])
+}
+
+// MetadataDomains returns the sorted names of the domains with dataset metadata.
+func (ip *ImageParams) MetadataDomains() []string {
+	return domainNames(ip.metadata)
+}
+
+// BandMetadata returns a copy of the band metadata of the default domain.
+func (ip *ImageParams) BandMetadata() map[string]string {
+	return copyItems(ip.bandMetadata[""])
+}
+
+// BandDomainMetadata returns a copy of the band metadata of a domain.
+func (ip *ImageParams) BandDomainMetadata(domain string) map[string]string {
+	return copyItems(ip.bandMetadata[domain])
+}
+
+// BandMetadataDomains returns the sorted names of the domains with band metadata.
+func (ip *ImageParams) BandMetadataDomains() []string {
+	return domainNames(ip.bandMetadata)
 }
 
 func (ip *ImageParams) NorthWest() LatLon {
@@ -105,17 +166,18 @@ func (ip *ImageParams) Value2time(v float64, ll LatLon) (time.Time, error) {
 
 func ImageParamsBuilder(xSize, ySize int) *imageParamsBuilder {
 	ip := &ImageParams{
-		xSize:      xSize,
-		ySize:      ySize,
-		transform:  AffineTransform{0, 1, 0, 0, 0, 1},
-		projection: ModisWKT,
-		offset:     0.0,
-		scale:      1.0,
-		nan:        math.NaN(),
-		nanPresent: false,
-		datatype:   gdal.Float64,
-		metadata:   make(map[string]string),
-		date:       time.Time{},
+		xSize:        xSize,
+		ySize:        ySize,
+		transform:    AffineTransform{0, 1, 0, 0, 0, 1},
+		projection:   ModisWKT,
+		offset:       0.0,
+		scale:        1.0,
+		nan:          math.NaN(),
+		nanPresent:   false,
+		datatype:     gdal.Float64,
+		metadata:     make(map[string]map[string]string),
+		bandMetadata: make(map[string]map[string]string),
+		date:         time.Time{},
 	}
 	return &imageParamsBuilder{ImageParams: ip}
 }
@@ -124,6 +186,12 @@ type imageParamsBuilder struct {
 	*ImageParams
 }
 
+func (ipb *imageParamsBuilder) Size(xSize, ySize int) *imageParamsBuilder {
+	ipb.xSize = xSize
+	ipb.ySize = ySize
+	return ipb
+}
+
 func (ipb *imageParamsBuilder) Transform(transform AffineTransform) *imageParamsBuilder {
 	ipb.transform = transform
 	return ipb
@@ -161,7 +229,22 @@ func (ipb *imageParamsBuilder) Date(tm time.Time) *imageParamsBuilder {
 }
 
 func (ipb *imageParamsBuilder) Metadata(key, value string) *imageParamsBuilder {
-	ipb.metadata[key] = value
+	setItem(ipb.metadata, "", key, value)
+	return ipb
+}
+
+func (ipb *imageParamsBuilder) DomainMetadata(domain, key, value string) *imageParamsBuilder {
+	setItem(ipb.metadata, domain, key, value)
+	return ipb
+}
+
+func (ipb *imageParamsBuilder) BandMetadata(key, value string) *imageParamsBuilder {
+	setItem(ipb.bandMetadata, "", key, value)
+	return ipb
+}
+
+func (ipb *imageParamsBuilder) BandDomainMetadata(domain, key, value string) *imageParamsBuilder {
+	setItem(ipb.bandMetadata, domain, key, value)
 	return ipb
 }
 
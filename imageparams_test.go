@@ -0,0 +1,43 @@
+package modis_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/nordicsense/modis"
+)
+
+func TestImageParams_Metadata(t *testing.T) {
+	b := modis.ImageParamsBuilder(2, 2).
+		Metadata("SOURCE", "MOD11A1").
+		DomainMetadata("PROVENANCE", "command", "modis-extract").
+		BandMetadata("units", "K").
+		BandDomainMetadata("QA", "spec", "MOD11 QC")
+	p := b.Build()
+	b.Metadata("SOURCE", "MYD11A1")
+	p.Metadata()["SOURCE"] = "changed"
+	p.BandMetadata()["units"] = "changed"
+
+	if md := p.Metadata(); !reflect.DeepEqual(md, map[string]string{"SOURCE": "MOD11A1"}) {
+		t.Errorf("unexpected metadata %v", md)
+	}
+	if domains := p.MetadataDomains(); !reflect.DeepEqual(domains, []string{"", "PROVENANCE"}) {
+		t.Errorf("unexpected domains %v", domains)
+	}
+	if md := p.DomainMetadata("PROVENANCE"); md["command"] != "modis-extract" {
+		t.Errorf("unexpected domain metadata %v", md)
+	}
+	if md := p.BandMetadata(); !reflect.DeepEqual(md, map[string]string{"units": "K"}) {
+		t.Errorf("unexpected band metadata %v", md)
+	}
+	if domains := p.BandMetadataDomains(); !reflect.DeepEqual(domains, []string{"", "QA"}) {
+		t.Errorf("unexpected band domains %v", domains)
+	}
+	if md := p.DomainMetadata("missing"); md == nil || len(md) != 0 {
+		t.Errorf("expected empty metadata, found %v", md)
+	}
+	q := p.ToBuilder().BandDomainMetadata("QA", "spec", "other").Build()
+	if p.BandDomainMetadata("QA")["spec"] != "MOD11 QC" || q.BandDomainMetadata("QA")["spec"] != "other" {
+		t.Error("expected builder to copy band metadata")
+	}
+}
@@ -0,0 +1,27 @@
+// Package cpl reads the error state of GDAL (CPL), which the GDAL bindings do not expose for
+// calls without a return value such as flushing and closing datasets.
+package cpl
+
+/*
+#cgo pkg-config: gdal
+#include "cpl_error.h"
+*/
+import "C"
+
+import (
+	"errors"
+	"runtime"
+)
+
+// Do runs fn and returns the last GDAL error of at least failure severity raised by it, if any.
+// GDAL keeps errors per thread, so fn runs locked to the OS thread the error state is read from.
+func Do(fn func()) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	C.CPLErrorReset()
+	fn()
+	if C.CPLGetLastErrorType() < C.CE_Failure {
+		return nil
+	}
+	return errors.New(C.GoString(C.CPLGetLastErrorMsg()))
+}
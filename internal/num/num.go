@@ -0,0 +1,52 @@
+// Package num holds numeric helpers shared by the packages of the module.
+package num
+
+import (
+	"math"
+	"strconv"
+)
+
+// IsFinite reports whether v is neither NaN nor infinite.
+func IsFinite(v float64) bool {
+	return !math.IsNaN(v) && !math.IsInf(v, 0)
+}
+
+func MinInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func MaxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+func AbsInt(a int) int {
+	if a < 0 {
+		return -a
+	}
+	return a
+}
+
+// JSONFloat marshals NaN and infinite values, which JSON cannot represent, as null.
+type JSONFloat float64
+
+func (f JSONFloat) MarshalJSON() ([]byte, error) {
+	v := float64(f)
+	if !IsFinite(v) {
+		return []byte("null"), nil
+	}
+	return []byte(strconv.FormatFloat(v, 'g', -1, 64)), nil
+}
+
+// FormatFloat formats v in the shortest exact form, NaN as an empty string.
+func FormatFloat(v float64) string {
+	if math.IsNaN(v) {
+		return ""
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,207 @@
+package qa
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/hdfeos"
+)
+
+var subdatasetPattern = regexp.MustCompile(`^SUBDATASET_\d+_NAME=(.+)$`)
+
+// Condition accepts pixels whose QA field has one of the listed values or, if Max is set, a
+// value up to *Max.
+type Condition struct {
+	Field  string
+	Accept []uint32
+	Max    *uint32
+}
+
+// In accepts the listed values of a field.
+func In(field string, values ...uint32) Condition {
+	return Condition{Field: field, Accept: values}
+}
+
+// AtMost accepts values of a field up to max, e.g. AtMost("lst_error", 0) for errors <= 1K.
+func AtMost(field string, max uint32) Condition {
+	return Condition{Field: field, Max: &max}
+}
+
+// Rule accepts pixels satisfying all of its conditions, e.g. MOD11 LST of good quality with
+// errors up to 1K:
+//
+//	qa.Rule{qa.In("mandatory_qa", 0), qa.AtMost("lst_error", 0)}
+type Rule []Condition
+
+// Rejections counts the pixels of a block rejected by the conditions of a rule keyed by field
+// name. A pixel failing several conditions counts for each, Total counts every pixel once.
+// Pixels already NaN in the value layer are not counted.
+type Rejections struct {
+	Box    modis.Box
+	Total  int
+	Counts map[string]int
+}
+
+// Options configure quality-masked readers.
+type Options struct {
+	// OnBlock, if set, receives the rejections of every block read.
+	OnBlock func(r *Rejections)
+}
+
+type condition struct {
+	field  *Field
+	accept map[uint32]bool
+	max    *uint32
+}
+
+func (c *condition) accepts(v uint32) bool {
+	return c.accept[v] || (c.max != nil && v <= *c.max)
+}
+
+// Mask returns a lazy view of values with pixels rejected by the rule evaluated on the aligned
+// QA layer set to NaN. Close closes neither of the readers.
+func Mask(values, qa dataset.Reader, spec *Spec, rule Rule, opts *Options) (dataset.Reader, error) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	if err := dataset.CheckAligned(values.ImageParams(), qa.ImageParams()); err != nil {
+		return nil, err
+	}
+	if err := checkIntegral(qa.ImageParams().DataType()); err != nil {
+		return nil, err
+	}
+	var conds []condition
+	for _, c := range rule {
+		f, err := spec.Field(c.Field)
+		if err != nil {
+			return nil, err
+		}
+		accept := make(map[uint32]bool)
+		for _, v := range c.Accept {
+			accept[v] = true
+		}
+		conds = append(conds, condition{field: f, accept: accept, max: c.Max})
+	}
+	bx, by := values.BlockSize()
+	return dataset.NewView(values.ImageParams(), bx, by, func(box modis.Box) ([]float64, error) {
+		res, err := values.ReadBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		raw, err := qa.ReadRawBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		rejections := &Rejections{Box: box, Counts: make(map[string]int)}
+		for i, v := range res {
+			if math.IsNaN(v) {
+				continue
+			}
+			q := uint32(raw.IntAt(i))
+			rejected := false
+			for _, c := range conds {
+				if !c.accepts(c.field.Decode(q)) {
+					rejections.Counts[c.field.Name]++
+					rejected = true
+				}
+			}
+			if rejected {
+				res[i] = math.NaN()
+				rejections.Total++
+			}
+		}
+		if opts.OnBlock != nil {
+			opts.OnBlock(rejections)
+		}
+		return res, nil
+	}), nil
+}
+
+// masked owns the files it masks.
+type masked struct {
+	dataset.Reader
+	files []dataset.Reader
+}
+
+func (m *masked) Close() error {
+	var err error
+	for _, f := range m.files {
+		if e := f.Close(); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// OpenMasked opens the value subdataset of an HDF file and the QA subdataset of the same file
+// matching qaPattern, e.g. "QC_Day$" for "LST_Day_1km", and masks values by the rule. If spec
+// is nil, the shipped spec matching the QA subdataset is used. Close closes both subdatasets.
+func OpenMasked(valueName, qaPattern string, spec *Spec, rule Rule, opts *Options) (dataset.Reader, error) {
+	matcher, err := regexp.Compile(qaPattern)
+	if err != nil {
+		return nil, err
+	}
+	sub, ok := hdfeos.ParseSubdataset(valueName)
+	if !ok {
+		return nil, fmt.Errorf("no file name in subdataset name %s", valueName)
+	}
+	fileName := sub.File
+	names, err := listSubdatasets(fileName)
+	if err != nil {
+		return nil, err
+	}
+	var qaName string
+	for _, name := range names {
+		if matcher.MatchString(name) {
+			if qaName != "" {
+				return nil, fmt.Errorf("QA pattern %s matches both %s and %s", qaPattern, qaName, name)
+			}
+			qaName = name
+		}
+	}
+	if qaName == "" {
+		return nil, fmt.Errorf("no QA subdataset matching %s in %s", qaPattern, fileName)
+	}
+	if spec == nil {
+		var ok bool
+		if spec, ok = SpecFor(qaName); !ok {
+			return nil, fmt.Errorf("no QA spec for %s", qaName)
+		}
+	}
+	values, err := dataset.Open(valueName)
+	if err != nil {
+		return nil, err
+	}
+	qa, err := dataset.Open(qaName)
+	if err != nil {
+		values.Close()
+		return nil, err
+	}
+	view, err := Mask(values, qa, spec, rule, opts)
+	if err != nil {
+		values.Close()
+		qa.Close()
+		return nil, err
+	}
+	return &masked{Reader: view, files: []dataset.Reader{values, qa}}, nil
+}
+
+// listSubdatasets lists the names of the subdatasets of an HDF file.
+func listSubdatasets(fileName string) ([]string, error) {
+	ds, err := gdal.Open(fileName, gdal.ReadOnly)
+	if err != nil {
+		return nil, err
+	}
+	defer ds.Close()
+	var res []string
+	for _, item := range ds.Metadata("SUBDATASETS") {
+		if m := subdatasetPattern.FindStringSubmatch(item); m != nil {
+			res = append(res, m[1])
+		}
+	}
+	return res, nil
+}
@@ -0,0 +1,154 @@
+// Package qa decodes MODIS quality assurance layers, which pack flags into bit ranges of
+// integer values, using declarative bitfield specs.
+package qa
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+)
+
+// Field is a flag stored in Length bits starting at bit Offset (0 being the least significant).
+type Field struct {
+	Name   string
+	Offset uint
+	Length uint
+	// Labels describe the values of the field, values without a label are described by their number.
+	Labels map[uint32]string
+}
+
+// Spec describes all fields of a QA layer.
+type Spec struct {
+	Name string
+	// Pattern matches the names of datasets (or HDF subdatasets) the spec applies to.
+	Pattern *regexp.Regexp
+	// Bits is the width of the QA values, 8 for bytes and 16 for 16 bit integers.
+	Bits   uint
+	Fields []Field
+}
+
+// Decode extracts the value of the field from a QA value.
+func (f *Field) Decode(v uint32) uint32 {
+	return (v >> f.Offset) & (1<<f.Length - 1)
+}
+
+// Label describes a value of the field.
+func (f *Field) Label(v uint32) string {
+	if label, ok := f.Labels[v]; ok {
+		return label
+	}
+	return strconv.FormatUint(uint64(v), 10)
+}
+
+// DecodeRaw extracts the values of the field from a block of integer QA values.
+func (f *Field) DecodeRaw(b *dataset.RawBuffer) ([]uint32, error) {
+	if err := checkIntegral(b.DataType()); err != nil {
+		return nil, err
+	}
+	res := make([]uint32, b.Len())
+	for i := range res {
+		res[i] = f.Decode(uint32(b.IntAt(i)))
+	}
+	return res, nil
+}
+
+// Field returns the field of given name.
+func (s *Spec) Field(name string) (*Field, error) {
+	for i := range s.Fields {
+		if s.Fields[i].Name == name {
+			return &s.Fields[i], nil
+		}
+	}
+	return nil, fmt.Errorf("no field %s in %s", name, s.Name)
+}
+
+// Decode extracts the values of all fields from a QA value.
+func (s *Spec) Decode(v uint32) map[string]uint32 {
+	res := make(map[string]uint32, len(s.Fields))
+	for i := range s.Fields {
+		res[s.Fields[i].Name] = s.Fields[i].Decode(v)
+	}
+	return res
+}
+
+// Describe labels the values of all fields of a QA value.
+func (s *Spec) Describe(v uint32) map[string]string {
+	res := make(map[string]string, len(s.Fields))
+	for i := range s.Fields {
+		f := &s.Fields[i]
+		res[f.Name] = f.Label(f.Decode(v))
+	}
+	return res
+}
+
+// Validate checks that fields have unique names and do not overlap or exceed the bits of the spec.
+func (s *Spec) Validate() error {
+	fields := append([]Field(nil), s.Fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Offset < fields[j].Offset })
+	names := make(map[string]bool)
+	for i, f := range fields {
+		if names[f.Name] {
+			return fmt.Errorf("%s: duplicate field %s", s.Name, f.Name)
+		}
+		names[f.Name] = true
+		if f.Length == 0 || f.Offset+f.Length > s.Bits {
+			return fmt.Errorf("%s: field %s of bits [%d,%d) outside of %d bits", s.Name, f.Name, f.Offset, f.Offset+f.Length, s.Bits)
+		}
+		if i > 0 && fields[i-1].Offset+fields[i-1].Length > f.Offset {
+			return fmt.Errorf("%s: fields %s and %s overlap", s.Name, fields[i-1].Name, f.Name)
+		}
+	}
+	return nil
+}
+
+// FlagReader returns a lazy view of the values of the field decoded from the raw values of
+// a QA layer. The view has the grid of r without scale, offset or NoData.
+func FlagReader(r dataset.Reader, f *Field) (dataset.Reader, error) {
+	src := r.ImageParams()
+	if err := checkIntegral(src.DataType()); err != nil {
+		return nil, err
+	}
+	dt := gdal.Byte
+	if f.Length > 8 {
+		dt = gdal.UInt32
+	}
+	b := modis.ImageParamsBuilder(src.XSize(), src.YSize()).
+		Transform(src.Transform()).
+		Projection(src.Projection()).
+		Date(src.Date()).
+		DataType(dt)
+	for _, domain := range src.MetadataDomains() {
+		for k, v := range src.DomainMetadata(domain) {
+			b.DomainMetadata(domain, k, v)
+		}
+	}
+	bx, by := r.BlockSize()
+	return dataset.NewView(b.Build(), bx, by, func(box modis.Box) ([]float64, error) {
+		raw, err := r.ReadRawBlock(0, 0, box)
+		if err != nil {
+			return nil, err
+		}
+		values, err := f.DecodeRaw(raw)
+		if err != nil {
+			return nil, err
+		}
+		res := make([]float64, len(values))
+		for i, v := range values {
+			res[i] = float64(v)
+		}
+		return res, nil
+	}), nil
+}
+
+func checkIntegral(dt gdal.DataType) error {
+	switch dt {
+	case gdal.Byte, gdal.Int16, gdal.UInt16, gdal.Int32, gdal.UInt32:
+		return nil
+	}
+	return fmt.Errorf("QA values of data type %s are not integral", dt.Name())
+}
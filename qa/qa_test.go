@@ -0,0 +1,155 @@
+package qa_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/qa"
+)
+
+func TestSpecs(t *testing.T) {
+	for _, s := range qa.Specs {
+		if err := s.Validate(); err != nil {
+			t.Error(err)
+		}
+	}
+	for name, expected := range map[string]*qa.Spec{
+		`HDF4_EOS:EOS_GRID:"MOD11A1.hdf":MODIS_Grid_Daily_1km_LST:QC_Day`:                 qa.MOD11QC,
+		`HDF4_EOS:EOS_GRID:"MOD13A2.hdf":MODIS_Grid_16DAY_1km_VI:1 km 16 days VI Quality`: qa.MOD13VIQuality,
+		`HDF4_EOS:EOS_GRID:"MOD09GA.hdf":MODIS_Grid_1km_2D:state_1km_1`:                   qa.MOD09State,
+	} {
+		if s, ok := qa.SpecFor(name); !ok || s != expected {
+			t.Errorf("expected %s for %s", expected.Name, name)
+		}
+	}
+	if _, ok := qa.SpecFor("LST_Day_1km"); ok {
+		t.Error("expected no spec for LST")
+	}
+	bad := &qa.Spec{Name: "bad", Bits: 8, Fields: []qa.Field{{Name: "a", Offset: 0, Length: 3}, {Name: "b", Offset: 2, Length: 2}}}
+	if err := bad.Validate(); err == nil {
+		t.Error("expected overlap error")
+	}
+}
+
+func TestDecode(t *testing.T) {
+	// LST error <= 2K, emissivity error <= 0.04, other quality data, LST produced with other quality
+	v := uint32(0x65)
+	expected := map[string]uint32{"mandatory_qa": 1, "data_quality": 1, "emissivity_error": 2, "lst_error": 1}
+	if values := qa.MOD11QC.Decode(v); !reflect.DeepEqual(values, expected) {
+		t.Errorf("expected %v, found %v", expected, values)
+	}
+	if labels := qa.MOD11QC.Describe(v); labels["lst_error"] != "average LST error <= 2K" {
+		t.Errorf("unexpected labels %v", labels)
+	}
+	f, err := qa.MOD13VIQuality.Field("vi_usefulness")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l := f.Label(f.Decode(7 << 2)); l != "7" {
+		t.Errorf("expected numeric label, found %s", l)
+	}
+	if _, err = qa.MOD13VIQuality.Field("unknown"); err == nil {
+		t.Error("expected error for unknown field")
+	}
+	// sign bit of signed 16 bit values
+	f, _ = qa.MOD09State.Field("internal_snow")
+	raw, _ := dataset.NewRawBuffer(gdal.Int16, 2)
+	raw.Set(0, -32768)
+	raw.Set(1, 32767)
+	if values, err := f.DecodeRaw(raw); err != nil || !reflect.DeepEqual(values, []uint32{1, 0}) {
+		t.Errorf("unexpected values %v (%v)", values, err)
+	}
+	raw, _ = dataset.NewRawBuffer(gdal.Float32, 1)
+	if _, err = f.DecodeRaw(raw); err == nil {
+		t.Error("expected error for float values")
+	}
+}
+
+func TestFlagReader(t *testing.T) {
+	p := modis.ImageParamsBuilder(3, 2).DataType(gdal.Byte).NaN(255).Build()
+	r := dataset.NewInMemory(p)
+	raw, _ := dataset.NewRawBuffer(gdal.Byte, 6)
+	for i, v := range []float64{0x00, 0x01, 0x02, 0x03, 0x41, 0xC2} {
+		raw.Set(i, v)
+	}
+	if err := r.WriteRawBlock(0, 0, modis.Box{0, 0, 3, 2}, raw); err != nil {
+		t.Fatal(err)
+	}
+	for name, expected := range map[string][]float64{
+		"mandatory_qa": {0, 1, 2, 3, 1, 2},
+		"lst_error":    {0, 0, 0, 0, 1, 3},
+	} {
+		f, _ := qa.MOD11QC.Field(name)
+		flags, err := qa.FlagReader(r, f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, ok := flags.ImageParams().NaN(); ok || flags.ImageParams().DataType() != gdal.Byte {
+			t.Errorf("unexpected flag parameters")
+		}
+		values, err := flags.ReadBlock(0, 0, modis.Box{0, 0, 3, 2})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(values, expected) {
+			t.Errorf("%s: expected %v, found %v", name, expected, values)
+		}
+	}
+}
+
+func TestMask(t *testing.T) {
+	p := modis.ImageParamsBuilder(3, 2).Build()
+	values := dataset.NewInMemory(p)
+	if err := values.WriteBlock(0, 0, modis.Box{0, 0, 3, 2}, []float64{300, 301, 302, 303, math.NaN(), 305}); err != nil {
+		t.Fatal(err)
+	}
+	quality := dataset.NewInMemory(p.ToBuilder().DataType(gdal.Byte).Build())
+	// good; other quality; cloud; good with error <= 2K; cloud (NaN value); other quality with error > 3K
+	raw, _ := dataset.NewRawBuffer(gdal.Byte, 6)
+	for i, v := range []float64{0x00, 0x01, 0x02, 0x40, 0x02, 0xC1} {
+		raw.Set(i, v)
+	}
+	if err := quality.WriteRawBlock(0, 0, modis.Box{0, 0, 3, 2}, raw); err != nil {
+		t.Fatal(err)
+	}
+	var rejections []*qa.Rejections
+	opts := &qa.Options{OnBlock: func(r *qa.Rejections) { rejections = append(rejections, r) }}
+	rule := qa.Rule{qa.In("mandatory_qa", 0, 1), qa.AtMost("lst_error", 0)}
+	r, err := qa.Mask(values, quality, qa.MOD11QC, rule, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := r.ReadBlock(0, 0, modis.Box{0, 0, 3, 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, expected := range []float64{300, 301, math.NaN(), math.NaN(), math.NaN(), math.NaN()} {
+		if v := res[i]; v != expected && !(math.IsNaN(v) && math.IsNaN(expected)) {
+			t.Errorf("expected %v at %d, found %v", expected, i, v)
+		}
+	}
+	if len(rejections) != 1 || rejections[0].Total != 3 || !reflect.DeepEqual(rejections[0].Counts, map[string]int{"mandatory_qa": 1, "lst_error": 2}) {
+		t.Errorf("unexpected rejections %+v", rejections[0])
+	}
+	// the bound covers the whole value range without enumerating it
+	r, err = qa.Mask(values, quality, qa.MOD11QC, qa.Rule{qa.AtMost("lst_error", math.MaxUint32)}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err = r.ReadBlock(0, 0, modis.Box{0, 0, 3, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if math.IsNaN(res[5]) {
+		t.Error("expected any error to be accepted")
+	}
+	if _, err = qa.Mask(values, quality, qa.MOD11QC, qa.Rule{qa.In("cloud_state", 0)}, nil); err == nil {
+		t.Error("expected error for unknown field")
+	}
+	if _, err = qa.Mask(values, values, qa.MOD11QC, rule, nil); err == nil {
+		t.Error("expected error for float QA")
+	}
+}
@@ -0,0 +1,130 @@
+package qa
+
+import "regexp"
+
+var noYes = map[uint32]string{0: "no", 1: "yes"}
+
+var landWater = map[uint32]string{
+	0: "shallow ocean",
+	1: "land",
+	2: "ocean coastlines and lake shorelines",
+	3: "shallow inland water",
+	4: "ephemeral water",
+	5: "deep inland water",
+	6: "moderate or continental ocean",
+	7: "deep ocean",
+}
+
+// MOD11QC describes the QC_Day and QC_Night layers of MOD11A1/MYD11A1 daily LST.
+var MOD11QC = &Spec{
+	Name:    "MOD11 QC",
+	Pattern: regexp.MustCompile(`QC_(Day|Night)$`),
+	Bits:    8,
+	Fields: []Field{
+		{Name: "mandatory_qa", Offset: 0, Length: 2, Labels: map[uint32]string{
+			0: "LST produced, good quality",
+			1: "LST produced, other quality",
+			2: "LST not produced due to cloud effects",
+			3: "LST not produced primarily due to reasons other than cloud",
+		}},
+		{Name: "data_quality", Offset: 2, Length: 2, Labels: map[uint32]string{
+			0: "good data quality",
+			1: "other quality data",
+		}},
+		{Name: "emissivity_error", Offset: 4, Length: 2, Labels: map[uint32]string{
+			0: "average emissivity error <= 0.01",
+			1: "average emissivity error <= 0.02",
+			2: "average emissivity error <= 0.04",
+			3: "average emissivity error > 0.04",
+		}},
+		{Name: "lst_error", Offset: 6, Length: 2, Labels: map[uint32]string{
+			0: "average LST error <= 1K",
+			1: "average LST error <= 2K",
+			2: "average LST error <= 3K",
+			3: "average LST error > 3K",
+		}},
+	},
+}
+
+// MOD13VIQuality describes the VI Quality layer of MOD13/MYD13 vegetation indices.
+var MOD13VIQuality = &Spec{
+	Name:    "MOD13 VI Quality",
+	Pattern: regexp.MustCompile(`VI Quality$`),
+	Bits:    16,
+	Fields: []Field{
+		{Name: "modland_qa", Offset: 0, Length: 2, Labels: map[uint32]string{
+			0: "VI produced with good quality",
+			1: "VI produced, but check other QA",
+			2: "pixel produced, but most probably cloudy",
+			3: "pixel not produced due to other reasons than clouds",
+		}},
+		{Name: "vi_usefulness", Offset: 2, Length: 4, Labels: map[uint32]string{
+			0:  "highest quality",
+			1:  "lower quality",
+			12: "lowest quality",
+			13: "quality so low that it is not useful",
+			14: "L1B data faulty",
+			15: "not useful for any other reason or not processed",
+		}},
+		{Name: "aerosol_quantity", Offset: 6, Length: 2, Labels: map[uint32]string{
+			0: "climatology",
+			1: "low",
+			2: "intermediate",
+			3: "high",
+		}},
+		{Name: "adjacent_cloud", Offset: 8, Length: 1, Labels: noYes},
+		{Name: "brdf_correction", Offset: 9, Length: 1, Labels: noYes},
+		{Name: "mixed_clouds", Offset: 10, Length: 1, Labels: noYes},
+		{Name: "land_water", Offset: 11, Length: 3, Labels: landWater},
+		{Name: "snow_ice", Offset: 14, Length: 1, Labels: noYes},
+		{Name: "shadow", Offset: 15, Length: 1, Labels: noYes},
+	},
+}
+
+// MOD09State describes the state_1km layer of MOD09GA/MYD09GA surface reflectance.
+var MOD09State = &Spec{
+	Name:    "MOD09 state_1km",
+	Pattern: regexp.MustCompile(`state_1km(_1)?$`),
+	Bits:    16,
+	Fields: []Field{
+		{Name: "cloud_state", Offset: 0, Length: 2, Labels: map[uint32]string{
+			0: "clear",
+			1: "cloudy",
+			2: "mixed",
+			3: "not set, assumed clear",
+		}},
+		{Name: "cloud_shadow", Offset: 2, Length: 1, Labels: noYes},
+		{Name: "land_water", Offset: 3, Length: 3, Labels: landWater},
+		{Name: "aerosol_quantity", Offset: 6, Length: 2, Labels: map[uint32]string{
+			0: "climatology",
+			1: "low",
+			2: "average",
+			3: "high",
+		}},
+		{Name: "cirrus", Offset: 8, Length: 2, Labels: map[uint32]string{
+			0: "none",
+			1: "small",
+			2: "average",
+			3: "high",
+		}},
+		{Name: "internal_cloud", Offset: 10, Length: 1, Labels: noYes},
+		{Name: "internal_fire", Offset: 11, Length: 1, Labels: noYes},
+		{Name: "snow_ice", Offset: 12, Length: 1, Labels: noYes},
+		{Name: "adjacent_cloud", Offset: 13, Length: 1, Labels: noYes},
+		{Name: "salt_pan", Offset: 14, Length: 1, Labels: noYes},
+		{Name: "internal_snow", Offset: 15, Length: 1, Labels: noYes},
+	},
+}
+
+// Specs lists the shipped specs.
+var Specs = []*Spec{MOD11QC, MOD13VIQuality, MOD09State}
+
+// SpecFor returns the shipped spec matching a dataset name, false if none does.
+func SpecFor(datasetName string) (*Spec, bool) {
+	for _, s := range Specs {
+		if s.Pattern.MatchString(datasetName) {
+			return s, true
+		}
+	}
+	return nil, false
+}
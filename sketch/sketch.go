@@ -0,0 +1,141 @@
+// Package sketch provides a streaming weighted quantile sketch with bounded memory, a merging
+// t-digest, used to compute medians and percentiles over images too large to sort.
+//
+// Centroids are kept small near the tails and the sketch is exact as long as the number of
+// values is small relative to the compression.
+package sketch
+
+import (
+	"math"
+	"sort"
+)
+
+// DefaultCompression gives an accuracy of well below 1% in rank for moderate memory.
+const DefaultCompression = 200
+
+type centroid struct {
+	mean   float64
+	weight float64
+}
+
+// Sketch accumulates weighted values, the zero value is not usable, see New.
+type Sketch struct {
+	compression float64
+	centroids   []centroid
+	buffer      []centroid
+	total       float64
+	min, max    float64
+}
+
+// New creates an empty sketch, a higher compression gives more accuracy for more memory.
+func New(compression float64) *Sketch {
+	if compression <= 0 {
+		compression = DefaultCompression
+	}
+	return &Sketch{compression: compression, min: math.Inf(1), max: math.Inf(-1)}
+}
+
+// Add adds a value with a positive weight, NaN values and non-positive weights are ignored.
+func (s *Sketch) Add(v, weight float64) {
+	if math.IsNaN(v) || !(weight > 0) {
+		return
+	}
+	s.buffer = append(s.buffer, centroid{mean: v, weight: weight})
+	s.total += weight
+	s.min, s.max = math.Min(s.min, v), math.Max(s.max, v)
+	if len(s.buffer) >= int(10*s.compression) {
+		s.compress()
+	}
+}
+
+// Merge adds all values of another sketch.
+func (s *Sketch) Merge(o *Sketch) {
+	o.compress()
+	s.buffer = append(s.buffer, o.centroids...)
+	s.total += o.total
+	s.min, s.max = math.Min(s.min, o.min), math.Max(s.max, o.max)
+	s.compress()
+}
+
+// Weight returns the total weight of all values.
+func (s *Sketch) Weight() float64 {
+	return s.total
+}
+
+// Min returns the smallest value, NaN if empty.
+func (s *Sketch) Min() float64 {
+	if s.total == 0 {
+		return math.NaN()
+	}
+	return s.min
+}
+
+// Max returns the largest value, NaN if empty.
+func (s *Sketch) Max() float64 {
+	if s.total == 0 {
+		return math.NaN()
+	}
+	return s.max
+}
+
+// Quantile returns the weighted quantile q in [0,1] interpolating between centroids, NaN if
+// empty.
+func (s *Sketch) Quantile(q float64) float64 {
+	s.compress()
+	if s.total == 0 || math.IsNaN(q) {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+	target := q * s.total
+	// every centroid represents its weight centred at its mean
+	prevPos, prevMean := 0.0, s.min
+	cum := 0.0
+	for _, c := range s.centroids {
+		pos := cum + c.weight/2
+		if target < pos {
+			return interpolate(prevPos, prevMean, pos, c.mean, target)
+		}
+		prevPos, prevMean = pos, c.mean
+		cum += c.weight
+	}
+	return interpolate(prevPos, prevMean, s.total, s.max, target)
+}
+
+func interpolate(x0, y0, x1, y1, x float64) float64 {
+	if x1 <= x0 {
+		return y1
+	}
+	return y0 + (y1-y0)*(x-x0)/(x1-x0)
+}
+
+// compress merges the buffer into the centroids keeping centroids around quantile q below
+// 4*total*q*(1-q)/compression in weight.
+func (s *Sketch) compress() {
+	if len(s.buffer) == 0 {
+		return
+	}
+	all := append(s.centroids, s.buffer...)
+	sort.Slice(all, func(i, j int) bool { return all[i].mean < all[j].mean })
+	res := make([]centroid, 0, len(s.centroids)+1)
+	cur := all[0]
+	cum := 0.0
+	for _, c := range all[1:] {
+		w := cur.weight + c.weight
+		q := (cum + w/2) / s.total
+		if w <= 4*s.total*q*(1-q)/s.compression {
+			cur.mean += (c.mean - cur.mean) * c.weight / w
+			cur.weight = w
+			continue
+		}
+		cum += cur.weight
+		res = append(res, cur)
+		cur = c
+	}
+	s.centroids = append(res, cur)
+	s.buffer = s.buffer[:0]
+}
@@ -0,0 +1,58 @@
+package sketch_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/nordicsense/modis/sketch"
+)
+
+func TestSketch_Exact(t *testing.T) {
+	s := sketch.New(0)
+	for _, v := range []float64{4, 1, 3, 2, math.NaN()} {
+		s.Add(v, 1)
+	}
+	if s.Weight() != 4 || s.Min() != 1 || s.Max() != 4 {
+		t.Errorf("unexpected weight %v, min %v, max %v", s.Weight(), s.Min(), s.Max())
+	}
+	for q, expected := range map[float64]float64{0: 1, 0.5: 2.5, 0.25: 1.5, 1: 4} {
+		if actual := s.Quantile(q); actual != expected {
+			t.Errorf("q=%v: expected %v, found %v", q, expected, actual)
+		}
+	}
+	w := sketch.New(0)
+	w.Add(1, 3)
+	w.Add(10, 1)
+	if actual := w.Quantile(0.5); actual >= 5.5 {
+		t.Errorf("expected weighted median towards 1, found %v", actual)
+	}
+	if !math.IsNaN(sketch.New(0).Quantile(0.5)) {
+		t.Error("expected NaN for empty sketch")
+	}
+}
+
+func TestSketch_Accuracy(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	a, b := sketch.New(100), sketch.New(100)
+	var values []float64
+	for i := 0; i < 200000; i++ {
+		v := rnd.NormFloat64()
+		values = append(values, v)
+		if i%2 == 0 {
+			a.Add(v, 1)
+		} else {
+			b.Add(v, 1)
+		}
+	}
+	a.Merge(b)
+	sort.Float64s(values)
+	for _, q := range []float64{0.01, 0.1, 0.5, 0.9, 0.99} {
+		actual := a.Quantile(q)
+		rank := float64(sort.SearchFloat64s(values, actual)) / float64(len(values))
+		if math.Abs(rank-q) > 0.005 {
+			t.Errorf("q=%v: value %v has rank %v", q, actual, rank)
+		}
+	}
+}
@@ -1,29 +1,42 @@
 package ts
 
 import (
+	"context"
 	"io/ioutil"
 	"path"
 	"regexp"
 )
 
 func ScanTree(root, pattern string) ([]string, error) {
+	return ScanTreeContext(context.Background(), root, pattern)
+}
+
+// ScanTreeContext is ScanTree checking ctx before every directory and file, it returns
+// ctx.Err() once ctx is cancelled.
+func ScanTreeContext(ctx context.Context, root, pattern string) ([]string, error) {
 	matcher, err := regexp.Compile(pattern)
 	if err != nil {
 		return nil, err
 	}
-	return scanWithMatcher(root, matcher)
+	return scanWithMatcher(ctx, root, matcher)
 }
 
-func scanWithMatcher(root string, matcher *regexp.Regexp) ([]string, error) {
+func scanWithMatcher(ctx context.Context, root string, matcher *regexp.Regexp) ([]string, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
 	var res []string
 	infos, err := ioutil.ReadDir(root)
 	if err != nil {
 		return nil, err
 	}
 	for _, info := range infos {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		fpath := path.Join(root, info.Name())
 		if info.IsDir() {
-			more, err := scanWithMatcher(fpath, matcher)
+			more, err := scanWithMatcher(ctx, fpath, matcher)
 			if err != nil {
 				return nil, err
 			}
@@ -1,6 +1,7 @@
 package ts
 
 import (
+	"context"
 	"regexp"
 	"time"
 
@@ -34,6 +35,12 @@ type patternMatcher struct {
 // root (recursive sub-folders) that match provided dataset name patterns. Only complete
 // pairs are returned (incomplete or fully missing do not trigger error).
 func ListAll(root string, layerPairPatterns ...LayerPair) ([]LayerPair, error) {
+	return ListAllContext(context.Background(), root, layerPairPatterns...)
+}
+
+// ListAllContext is ListAll checking ctx while scanning and before every HDF file, it returns
+// ctx.Err() once ctx is cancelled.
+func ListAllContext(ctx context.Context, root string, layerPairPatterns ...LayerPair) ([]LayerPair, error) {
 	var matchers []patternMatcher
 	for _, layerPairPattern := range layerPairPatterns {
 		timeMatcher, err := regexp.Compile(layerPairPattern.Time)
@@ -47,13 +54,16 @@ func ListAll(root string, layerPairPatterns ...LayerPair) ([]LayerPair, error) {
 		matchers = append(matchers, patternMatcher{timeMatcher: timeMatcher, valueMatcher: valueMatcher})
 	}
 
-	hdfDSNames, err := ScanTree(root, hdfPattern)
+	hdfDSNames, err := ScanTreeContext(ctx, root, hdfPattern)
 	if err != nil {
 		return nil, err
 	}
 
 	var layerPairs []LayerPair
 	for _, hdfDSName := range hdfDSNames {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
 		pairs, err := getPairs(hdfDSName, matchers)
 		if err != nil {
 			return layerPairs, err
@@ -124,6 +124,18 @@ func (at AffineTransform) LatLon2Pixels(ll LatLon) (int, int) {
 	return at.LatLonSin2Pixels(ll)
 }
 
+// Invert returns the transform from coordinates to image pixels as fractional values.
+func (at AffineTransform) Invert() (AffineTransform, error) {
+	det := at[1]*at[5] - at[2]*at[4]
+	if det == 0 {
+		return at, fmt.Errorf("transform %v is not invertible", at)
+	}
+	return AffineTransform{
+		(at[2]*at[3] - at[0]*at[5]) / det, at[5] / det, -at[2] / det,
+		(at[0]*at[4] - at[1]*at[3]) / det, -at[4] / det, at[1] / det,
+	}, nil
+}
+
 // ModisLST2UTC transforms MODIS time values in hours given from Local Solar Time to UTC.
 func ModisLST2UTC(lst, lonDegree float64) float64 {
 	offset := 0.0
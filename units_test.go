@@ -84,3 +84,19 @@ func TestAffineTransform_LatLon2Pixels(t *testing.T) {
 		t.Errorf("expected (292,1171), found (%d, %d)", x, y)
 	}
 }
+
+func TestAffineTransform_Invert(t *testing.T) {
+	at := modis.AffineTransform{1000, 10, 2, 5000, 1, -10}
+	inv, err := at.Invert()
+	if err != nil {
+		t.Fatal(err)
+	}
+	x, y := 7.0, 3.0
+	cx, cy := at[0]+x*at[1]+y*at[2], at[3]+x*at[4]+y*at[5]
+	if px, py := inv[0]+cx*inv[1]+cy*inv[2], inv[3]+cx*inv[4]+cy*inv[5]; math.Abs(px-x) > 1e-9 || math.Abs(py-y) > 1e-9 {
+		t.Errorf("expected (%v,%v), found (%v,%v)", x, y, px, py)
+	}
+	if _, err = (modis.AffineTransform{0, 1, 1, 0, 1, 1}).Invert(); err == nil {
+		t.Error("expected error for singular transform")
+	}
+}
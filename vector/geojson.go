@@ -0,0 +1,127 @@
+// Package vector reads polygon features from GeoJSON and rasterizes them onto image grids,
+// e.g. to mask catchments or administrative areas.
+package vector
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/nordicsense/modis"
+)
+
+// Feature is a polygonal feature: a polygon or the parts of a multi-polygon.
+type Feature struct {
+	// ID is the numeric GeoJSON id or, if absent, the 1-based position of the feature.
+	ID         int
+	Properties map[string]interface{}
+	Polygons   []modis.Polygon
+}
+
+type geoJSON struct {
+	Type        string                 `json:"type"`
+	ID          interface{}            `json:"id"`
+	Properties  map[string]interface{} `json:"properties"`
+	Features    []geoJSON              `json:"features"`
+	Geometry    *geoJSON               `json:"geometry"`
+	Coordinates json.RawMessage        `json:"coordinates"`
+}
+
+// ReadGeoJSON reads Polygon and MultiPolygon features of a GeoJSON FeatureCollection, Feature
+// or geometry with coordinates in WGS84 degrees. Features without geometry are skipped, other
+// geometry types are rejected.
+func ReadGeoJSON(r io.Reader) ([]Feature, error) {
+	var doc geoJSON
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, err
+	}
+	var items []geoJSON
+	switch doc.Type {
+	case "FeatureCollection":
+		items = doc.Features
+	case "Feature":
+		items = []geoJSON{doc}
+	default:
+		items = []geoJSON{{Type: "Feature", Geometry: &doc}}
+	}
+	var res []Feature
+	for i, item := range items {
+		if item.Type != "Feature" {
+			return nil, fmt.Errorf("feature %d: unexpected type %s", i, item.Type)
+		}
+		if item.Geometry == nil {
+			continue
+		}
+		polygons, err := item.Geometry.polygons()
+		if err != nil {
+			return nil, fmt.Errorf("feature %d: %v", i, err)
+		}
+		f := Feature{ID: i + 1, Properties: item.Properties, Polygons: polygons}
+		if id, ok := item.ID.(float64); ok {
+			f.ID = int(id)
+		}
+		res = append(res, f)
+	}
+	return res, nil
+}
+
+// ReadGeoJSONFile reads features from a GeoJSON file, see ReadGeoJSON.
+func ReadGeoJSONFile(fileName string) ([]Feature, error) {
+	f, err := os.Open(fileName)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadGeoJSON(f)
+}
+
+func (g *geoJSON) polygons() ([]modis.Polygon, error) {
+	switch g.Type {
+	case "Polygon":
+		var coords [][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		pg, err := toPolygon(coords)
+		if err != nil {
+			return nil, err
+		}
+		return []modis.Polygon{pg}, nil
+	case "MultiPolygon":
+		var coords [][][][]float64
+		if err := json.Unmarshal(g.Coordinates, &coords); err != nil {
+			return nil, err
+		}
+		var res []modis.Polygon
+		for _, c := range coords {
+			pg, err := toPolygon(c)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, pg)
+		}
+		return res, nil
+	}
+	return nil, fmt.Errorf("unsupported geometry type %s", g.Type)
+}
+
+// toPolygon converts GeoJSON rings of [lon, lat] positions.
+func toPolygon(coords [][][]float64) (modis.Polygon, error) {
+	if len(coords) == 0 {
+		return nil, fmt.Errorf("polygon without rings")
+	}
+	res := make(modis.Polygon, len(coords))
+	for i, ring := range coords {
+		if len(ring) < 3 {
+			return nil, fmt.Errorf("ring with %d positions", len(ring))
+		}
+		for _, pos := range ring {
+			if len(pos) < 2 {
+				return nil, fmt.Errorf("position with %d coordinates", len(pos))
+			}
+			res[i] = append(res[i], modis.LatLon{pos[1], pos[0]})
+		}
+	}
+	return res, nil
+}
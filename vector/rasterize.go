@@ -0,0 +1,289 @@
+package vector
+
+import (
+	"errors"
+	"math"
+	"sort"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// Burn defines the values burnt into the raster for pixels covered by features.
+type Burn int
+
+const (
+	// Mask burns 1 for covered pixels.
+	Mask Burn = iota
+	// FeatureID burns the ID of the feature, later features overwrite earlier ones (see
+	// RasterizeEach for overlapping features).
+	FeatureID
+)
+
+const rasterizeBlockLines = 64
+
+var errTransform = errors.New("failed to transform coordinates into the image projection")
+
+// Options define how features are rasterized: by default pixels are covered if their centre
+// is inside of a polygon, with AllTouched all pixels touched by a polygon are covered.
+type Options struct {
+	Burn       Burn
+	AllTouched bool
+}
+
+// Rasterize burns features onto the grid of dst with 0 for uncovered pixels. Coordinates are
+// converted into the projection of the image.
+func Rasterize(dst dataset.Writer, features []Feature, opts *Options) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	p := dst.ImageParams()
+	pfs, err := toPixels(p, features, opts.Burn)
+	if err != nil {
+		return err
+	}
+	nx := p.XSize()
+	for y0 := 0; y0 < p.YSize(); y0 += rasterizeBlockLines {
+		s := &strip{nx: nx, y0: y0, ny: num.MinInt(rasterizeBlockLines, p.YSize()-y0)}
+		s.data = make([]float64, nx*s.ny)
+		for _, pf := range pfs {
+			if !s.intersects(pf) {
+				continue
+			}
+			value := pf.value
+			set := func(i int) { s.data[i] = value }
+			s.fill(pf, set)
+			if opts.AllTouched {
+				s.touch(pf, set)
+			}
+		}
+		if err = dst.WriteBlock(0, 0, modis.Box{0, y0, nx, s.ny}, s.data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// RasterizeEach rasterizes every feature separately onto the grid of p strip by strip, so that
+// pixels shared by overlapping features are covered by all of them. fn is called for every
+// strip and feature covering any of its pixels with the index of the feature and the indices
+// of the covered pixels within the strip (row by row), the Burn option is ignored.
+func RasterizeEach(p *modis.ImageParams, features []Feature, opts *Options, fn func(box modis.Box, feature int, pixels []int) error) error {
+	if opts == nil {
+		opts = &Options{}
+	}
+	pfs, err := toPixels(p, features, Mask)
+	if err != nil {
+		return err
+	}
+	nx := p.XSize()
+	// marks holds the last feature (1-based) covering a pixel so that pixels are listed once
+	marks := make([]int, nx*num.MinInt(rasterizeBlockLines, p.YSize()))
+	var pixels []int
+	for y0 := 0; y0 < p.YSize(); y0 += rasterizeBlockLines {
+		s := &strip{nx: nx, y0: y0, ny: num.MinInt(rasterizeBlockLines, p.YSize()-y0)}
+		for i := range marks {
+			marks[i] = 0
+		}
+		for k, pf := range pfs {
+			if !s.intersects(pf) {
+				continue
+			}
+			pixels = pixels[:0]
+			set := func(i int) {
+				if marks[i] != k+1 {
+					marks[i] = k + 1
+					pixels = append(pixels, i)
+				}
+			}
+			s.fill(pf, set)
+			if opts.AllTouched {
+				s.touch(pf, set)
+			}
+			if len(pixels) == 0 {
+				continue
+			}
+			sort.Ints(pixels)
+			if err = fn(modis.Box{0, y0, nx, s.ny}, k, pixels); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// RasterizeToMemory rasterizes features into a new in-memory Int32 image on the grid of p.
+func RasterizeToMemory(features []Feature, p *modis.ImageParams, opts *Options) (dataset.Reader, error) {
+	res := dataset.NewInMemory(p.ToBuilder().DataType(gdal.Int32).Scale(1).Offset(0).Build())
+	if err := Rasterize(res, features, opts); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// pixelFeature holds the rings of a feature in fractional pixel coordinates of the image.
+type pixelFeature struct {
+	value      float64
+	rings      [][][2]float64
+	minY, maxY float64
+}
+
+func toPixels(p *modis.ImageParams, features []Feature, burn Burn) ([]pixelFeature, error) {
+	inv, err := p.Transform().Invert()
+	if err != nil {
+		return nil, err
+	}
+	from := gdal.CreateSpatialReference("")
+	defer from.Destroy()
+	if err := from.FromEPSG(4326); err != nil {
+		return nil, err
+	}
+	to := gdal.CreateSpatialReference(p.Projection())
+	defer to.Destroy()
+	ct := gdal.CreateCoordinateTransform(from, to)
+	defer ct.Destroy()
+
+	var res []pixelFeature
+	for _, f := range features {
+		pf := pixelFeature{value: 1, minY: math.Inf(1), maxY: math.Inf(-1)}
+		if burn == FeatureID {
+			pf.value = float64(f.ID)
+		}
+		for _, pg := range f.Polygons {
+			for _, ring := range pg {
+				xs, ys, zs := make([]float64, len(ring)), make([]float64, len(ring)), make([]float64, len(ring))
+				for i, ll := range ring {
+					xs[i], ys[i] = ll[1], ll[0]
+				}
+				if !ct.Transform(len(ring), xs, ys, zs) {
+					return nil, errTransform
+				}
+				pr := make([][2]float64, len(ring))
+				for i := range ring {
+					x := inv[0] + xs[i]*inv[1] + ys[i]*inv[2]
+					y := inv[3] + xs[i]*inv[4] + ys[i]*inv[5]
+					pr[i] = [2]float64{x, y}
+					pf.minY, pf.maxY = math.Min(pf.minY, y), math.Max(pf.maxY, y)
+				}
+				pf.rings = append(pf.rings, pr)
+			}
+		}
+		res = append(res, pf)
+	}
+	return res, nil
+}
+
+// strip is a buffer of full image rows [y0, y0+ny).
+type strip struct {
+	nx, y0, ny int
+	data       []float64
+}
+
+func (s *strip) intersects(pf pixelFeature) bool {
+	return pf.maxY >= float64(s.y0) && pf.minY <= float64(s.y0+s.ny)
+}
+
+// fill burns pixels with centres inside of the feature scanning rows with the even-odd rule
+// over all rings, so that holes and overlapping parts of multi-polygons are excluded. set is
+// called with the index of every burnt pixel in the strip.
+func (s *strip) fill(pf pixelFeature, set func(i int)) {
+	var xs []float64
+	for j := 0; j < s.ny; j++ {
+		yc := float64(s.y0+j) + 0.5
+		xs = xs[:0]
+		for _, ring := range pf.rings {
+			for i, k := 0, len(ring)-1; i < len(ring); k, i = i, i+1 {
+				a, b := ring[i], ring[k]
+				if (a[1] > yc) != (b[1] > yc) {
+					xs = append(xs, a[0]+(yc-a[1])*(b[0]-a[0])/(b[1]-a[1]))
+				}
+			}
+		}
+		sort.Float64s(xs)
+		for k := 0; k+1 < len(xs); k += 2 {
+			from := num.MaxInt(int(math.Ceil(xs[k]-0.5)), 0)
+			to := num.MinInt(int(math.Ceil(xs[k+1]-0.5)), s.nx)
+			for i := from; i < to; i++ {
+				set(j*s.nx + i)
+			}
+		}
+	}
+}
+
+// touch burns all pixels crossed by the edges of the feature, see fill.
+func (s *strip) touch(pf pixelFeature, set func(i int)) {
+	for _, ring := range pf.rings {
+		for i, k := 0, len(ring)-1; i < len(ring); k, i = i, i+1 {
+			a, b, ok := clipSegment(ring[k], ring[i], 0, float64(s.y0), float64(s.nx), float64(s.y0+s.ny))
+			if ok {
+				traverse(a, b, func(x, y int) {
+					if x >= 0 && x < s.nx && y >= s.y0 && y < s.y0+s.ny {
+						set((y-s.y0)*s.nx + x)
+					}
+				})
+			}
+		}
+	}
+}
+
+// clipSegment clips the segment to the rectangle [x0,x1]x[y0,y1] (Liang-Barsky).
+func clipSegment(a, b [2]float64, x0, y0, x1, y1 float64) ([2]float64, [2]float64, bool) {
+	if !num.IsFinite(a[0]) || !num.IsFinite(a[1]) || !num.IsFinite(b[0]) || !num.IsFinite(b[1]) {
+		return a, b, false
+	}
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	t0, t1 := 0.0, 1.0
+	for _, c := range [][2]float64{{-dx, a[0] - x0}, {dx, x1 - a[0]}, {-dy, a[1] - y0}, {dy, y1 - a[1]}} {
+		p, q := c[0], c[1]
+		if p == 0 {
+			if q < 0 {
+				return a, b, false
+			}
+			continue
+		}
+		r := q / p
+		if p < 0 {
+			t0 = math.Max(t0, r)
+		} else {
+			t1 = math.Min(t1, r)
+		}
+	}
+	if t0 > t1 {
+		return a, b, false
+	}
+	return [2]float64{a[0] + t0*dx, a[1] + t0*dy}, [2]float64{a[0] + t1*dx, a[1] + t1*dy}, true
+}
+
+// traverse visits all pixels crossed by the segment (Amanatides-Woo).
+func traverse(a, b [2]float64, visit func(x, y int)) {
+	x, y := int(math.Floor(a[0])), int(math.Floor(a[1]))
+	xEnd, yEnd := int(math.Floor(b[0])), int(math.Floor(b[1]))
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	stepX, tMaxX, tDeltaX := axis(a[0], dx)
+	stepY, tMaxY, tDeltaY := axis(a[1], dy)
+	visit(x, y)
+	for n := num.AbsInt(xEnd-x) + num.AbsInt(yEnd-y); n > 0; n-- {
+		if tMaxX < tMaxY {
+			x += stepX
+			tMaxX += tDeltaX
+		} else {
+			y += stepY
+			tMaxY += tDeltaY
+		}
+		visit(x, y)
+	}
+}
+
+// axis returns the step, the parameter of the first pixel boundary and the parameter step
+// between pixel boundaries along one axis of a segment.
+func axis(start, delta float64) (int, float64, float64) {
+	switch {
+	case delta > 0:
+		return 1, (math.Floor(start) + 1 - start) / delta, 1 / delta
+	case delta < 0:
+		return -1, (start - math.Floor(start)) / -delta, 1 / -delta
+	}
+	return 0, math.Inf(1), math.Inf(1)
+}
@@ -0,0 +1,104 @@
+package vector_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/vector"
+)
+
+const collection = `{
+  "type": "FeatureCollection",
+  "features": [
+    {"type": "Feature", "id": 7, "properties": {"name": "a"},
+     "geometry": {"type": "Polygon", "coordinates": [[[0.7, 0.7], [9.3, 0.7], [9.3, 9.3], [0.7, 9.3], [0.7, 0.7]],
+                                                      [[3.7, 3.7], [6.3, 3.7], [6.3, 6.3], [3.7, 6.3], [3.7, 3.7]]]}},
+    {"type": "Feature", "properties": {"name": "b"},
+     "geometry": {"type": "MultiPolygon", "coordinates": [[[[12.2, 0.2], [13.8, 0.2], [13.8, 1.3], [12.2, 0.2]]],
+                                                           [[[12.2, 8.2], [13.8, 8.2], [13.8, 9.8], [12.2, 9.8]]]]}},
+    {"type": "Feature", "properties": {}, "geometry": null}
+  ]
+}`
+
+// geographic returns a 16x12 grid of 1 degree pixels from lon 0 and lat 10 down to -2.
+func geographic() *modis.ImageParams {
+	sr := gdal.CreateSpatialReference("")
+	defer sr.Destroy()
+	_ = sr.FromEPSG(4326)
+	wkt, _ := sr.ToWKT()
+	return modis.ImageParamsBuilder(16, 12).Transform(modis.AffineTransform{0, 1, 0, 10, 0, -1}).Projection(wkt).Build()
+}
+
+func TestReadGeoJSON(t *testing.T) {
+	features, err := vector.ReadGeoJSON(strings.NewReader(collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 2 {
+		t.Fatalf("expected 2 features, found %d", len(features))
+	}
+	if features[0].ID != 7 || features[0].Properties["name"] != "a" || len(features[0].Polygons) != 1 || len(features[0].Polygons[0]) != 2 {
+		t.Errorf("unexpected feature %+v", features[0])
+	}
+	if features[1].ID != 2 || len(features[1].Polygons) != 2 {
+		t.Errorf("unexpected feature %+v", features[1])
+	}
+	if !features[0].Polygons[0].Contains(modis.LatLon{2, 2}) || features[0].Polygons[0].Contains(modis.LatLon{5, 5}) {
+		t.Error("expected coordinates as lon, lat with a hole")
+	}
+	for _, doc := range []string{
+		`{"type": "Point", "coordinates": [1, 2]}`,
+		`{"type": "Polygon", "coordinates": [[[1, 2], [2, 3]]]}`,
+		`{"type": "Polygon"`,
+	} {
+		if _, err = vector.ReadGeoJSON(strings.NewReader(doc)); err == nil {
+			t.Errorf("expected error for %s", doc)
+		}
+	}
+}
+
+func TestRasterize(t *testing.T) {
+	features, err := vector.ReadGeoJSON(strings.NewReader(collection))
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := geographic()
+	ids, err := vector.RasterizeToMemory(features, p, &vector.Options{Burn: vector.FeatureID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := []struct {
+		x, y     int
+		expected float64
+	}{
+		{0, 0, 0}, {1, 1, 7}, {8, 8, 7}, {9, 9, 0}, {5, 5, 0}, {4, 4, 0}, {3, 3, 7}, {10, 0, 0},
+		{12, 0, 2}, {13, 1, 2}, {13, 9, 2}, {12, 9, 0}, {13, 8, 0}, {0, 10, 0},
+	}
+	for _, c := range cases {
+		if v, _ := ids.Read(c.x, c.y); v != c.expected {
+			t.Errorf("centre: expected %v at (%d,%d), found %v", c.expected, c.x, c.y, v)
+		}
+	}
+	if ids.ImageParams().DataType() != gdal.Int32 {
+		t.Errorf("expected Int32, found %v", ids.ImageParams().DataType())
+	}
+
+	touched, err := vector.RasterizeToMemory(features, p, &vector.Options{AllTouched: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases = []struct {
+		x, y     int
+		expected float64
+	}{
+		{0, 0, 1}, {9, 9, 1}, {5, 5, 0}, {4, 4, 0}, {3, 3, 1}, {10, 0, 0},
+		{12, 9, 1}, {13, 8, 1}, {12, 8, 0}, {11, 5, 0}, {0, 11, 0},
+	}
+	for _, c := range cases {
+		if v, _ := touched.Read(c.x, c.y); v != c.expected {
+			t.Errorf("all touched: expected %v at (%d,%d), found %v", c.expected, c.x, c.y, v)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package zonal
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"io"
+	"strconv"
+
+	"github.com/nordicsense/modis/internal/num"
+)
+
+// WriteCSV writes one row per zone with a header, percentile columns are named by level
+// (e.g. p10, p90) and NaN values are written as empty fields.
+func (r *Result) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	header := []string{"zone", "count", "valid", "weight", "mean", "std", "min", "max", "median"}
+	for _, level := range r.Percentiles {
+		header = append(header, "p"+num.FormatFloat(level))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, s := range r.Zones {
+		row := []string{strconv.Itoa(s.Zone), strconv.Itoa(s.Count), strconv.Itoa(s.Valid)}
+		for _, v := range append([]float64{s.Weight, s.Mean, s.Std, s.Min, s.Max, s.Median}, s.Percentiles...) {
+			row = append(row, num.FormatFloat(v))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// WriteJSON writes the result as a JSON document with NaN values as null.
+func (r *Result) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+// MarshalJSON encodes NaN values as null, which JSON has no number for.
+func (s Stats) MarshalJSON() ([]byte, error) {
+	var percentiles []num.JSONFloat
+	for _, v := range s.Percentiles {
+		percentiles = append(percentiles, num.JSONFloat(v))
+	}
+	return json.Marshal(struct {
+		Zone        int             `json:"zone"`
+		Count       int             `json:"count"`
+		Valid       int             `json:"valid"`
+		Weight      num.JSONFloat   `json:"weight"`
+		Mean        num.JSONFloat   `json:"mean"`
+		Std         num.JSONFloat   `json:"std"`
+		Min         num.JSONFloat   `json:"min"`
+		Max         num.JSONFloat   `json:"max"`
+		Median      num.JSONFloat   `json:"median"`
+		Percentiles []num.JSONFloat `json:"percentiles,omitempty"`
+	}{s.Zone, s.Count, s.Valid, num.JSONFloat(s.Weight), num.JSONFloat(s.Mean), num.JSONFloat(s.Std),
+		num.JSONFloat(s.Min), num.JSONFloat(s.Max), num.JSONFloat(s.Median), percentiles})
+}
@@ -0,0 +1,202 @@
+// Package zonal computes statistics of image values per zone, with zones given by an aligned
+// zone-ID raster or by polygon features. Images are processed block by block so that memory
+// use is bounded by the number of zones rather than the size of the image.
+package zonal
+
+import (
+	"fmt"
+	"math"
+	"sort"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/sketch"
+	"github.com/nordicsense/modis/vector"
+)
+
+// Options define the statistics computed per zone.
+type Options struct {
+	// Percentiles in [0,100] computed in addition to the median.
+	Percentiles []float64
+	// AreaWeighted weights values by pixel area: proportional to the area of the latitude band
+	// for geographic grids, constant (equal area, e.g. MODIS sinusoidal) for projected ones.
+	AreaWeighted bool
+	// Compression of the quantile sketches, see sketch.New.
+	Compression float64
+}
+
+// Stats are the statistics of a zone, statistics of zones without valid values are NaN.
+type Stats struct {
+	Zone int
+	// Count is the number of pixels of the zone, Valid the number of those with non-NaN values.
+	Count int
+	Valid int
+	// Weight is the sum of weights of valid pixels, equal to Valid unless area weighted.
+	Weight      float64
+	Mean        float64
+	Std         float64
+	Min         float64
+	Max         float64
+	Median      float64
+	Percentiles []float64
+}
+
+// Result holds the statistics of all zones ordered by zone ID.
+type Result struct {
+	Percentiles []float64 `json:"percentile_levels,omitempty"`
+	Zones       []Stats   `json:"zones"`
+}
+
+// ByRaster computes statistics of values per zone given by an aligned raster of zone IDs;
+// pixels with zone ID 0 or NaN are not part of any zone.
+func ByRaster(values, zones dataset.Reader, opts *Options) (*Result, error) {
+	if err := dataset.CheckAligned(values.ImageParams(), zones.ImageParams()); err != nil {
+		return nil, err
+	}
+	acc, err := newAccumulator(values.ImageParams(), opts)
+	if err != nil {
+		return nil, err
+	}
+	it := values.Blocks(0)
+	for it.Next() {
+		ids, err := zones.ReadBlock(0, 0, it.Box())
+		if err != nil {
+			return nil, err
+		}
+		acc.add(it.Box(), it.Data(), ids)
+	}
+	if err = it.Err(); err != nil {
+		return nil, err
+	}
+	return acc.result(), nil
+}
+
+// ByFeatures computes statistics of values per feature (by feature ID) rasterizing every feature
+// separately onto the grid of values strip by strip, see vector.RasterizeEach. Pixels covered by
+// overlapping features count toward each of them.
+func ByFeatures(values dataset.Reader, features []vector.Feature, rasterize *vector.Options, opts *Options) (*Result, error) {
+	acc, err := newAccumulator(values.ImageParams(), opts)
+	if err != nil {
+		return nil, err
+	}
+	// values of the current strip, read once for all features covering it
+	var box modis.Box
+	var strip []float64
+	err = vector.RasterizeEach(values.ImageParams(), features, rasterize, func(b modis.Box, feature int, pixels []int) error {
+		if strip == nil || b != box {
+			var err error
+			if strip, err = values.ReadBlock(0, 0, b); err != nil {
+				return err
+			}
+			box = b
+		}
+		z := acc.zone(features[feature].ID)
+		for _, i := range pixels {
+			acc.addValue(z, box[1]+i/box[2], strip[i])
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return acc.result(), nil
+}
+
+type zone struct {
+	count, valid int
+	weight       float64
+	mean, m2     float64
+	sketch       *sketch.Sketch
+}
+
+type accumulator struct {
+	opts  Options
+	zones map[int]*zone
+	// rowWeight gives the pixel weight of a row, nil if not area weighted
+	rowWeight func(y int) float64
+}
+
+func newAccumulator(p *modis.ImageParams, opts *Options) (*accumulator, error) {
+	res := &accumulator{zones: make(map[int]*zone)}
+	if opts != nil {
+		res.opts = *opts
+	}
+	for _, level := range res.opts.Percentiles {
+		if level < 0 || level > 100 {
+			return nil, fmt.Errorf("percentile %v outside of [0,100]", level)
+		}
+	}
+	if res.opts.AreaWeighted {
+		sr := gdal.CreateSpatialReference(p.Projection())
+		geographic := sr.IsGeographic()
+		sr.Destroy()
+		at := p.Transform()
+		if geographic {
+			if at[2] != 0 || at[4] != 0 {
+				return nil, fmt.Errorf("area weighting of rotated geographic grids not supported")
+			}
+			res.rowWeight = func(y int) float64 {
+				lat0 := (at[3] + float64(y)*at[5]) * math.Pi / 180
+				lat1 := (at[3] + float64(y+1)*at[5]) * math.Pi / 180
+				return math.Abs(math.Sin(lat0) - math.Sin(lat1))
+			}
+		}
+	}
+	return res, nil
+}
+
+func (acc *accumulator) add(box modis.Box, values, ids []float64) {
+	for i, id := range ids {
+		if math.IsNaN(id) || id == 0 {
+			continue
+		}
+		acc.addValue(acc.zone(int(id)), box[1]+i/box[2], values[i])
+	}
+}
+
+func (acc *accumulator) zone(id int) *zone {
+	z, ok := acc.zones[id]
+	if !ok {
+		z = &zone{sketch: sketch.New(acc.opts.Compression)}
+		acc.zones[id] = z
+	}
+	return z
+}
+
+// addValue adds a pixel of given image row to a zone.
+func (acc *accumulator) addValue(z *zone, row int, v float64) {
+	z.count++
+	if math.IsNaN(v) {
+		return
+	}
+	w := 1.0
+	if acc.rowWeight != nil {
+		w = acc.rowWeight(row)
+	}
+	// weighted incremental mean and variance (West)
+	z.valid++
+	z.weight += w
+	delta := v - z.mean
+	z.mean += delta * w / z.weight
+	z.m2 += w * delta * (v - z.mean)
+	z.sketch.Add(v, w)
+}
+
+func (acc *accumulator) result() *Result {
+	res := &Result{Percentiles: append([]float64(nil), acc.opts.Percentiles...)}
+	for id, z := range acc.zones {
+		s := Stats{Zone: id, Count: z.count, Valid: z.valid, Weight: z.weight, Mean: math.NaN(), Std: math.NaN()}
+		if z.valid > 0 {
+			s.Mean = z.mean
+			s.Std = math.Sqrt(z.m2 / z.weight)
+		}
+		s.Min, s.Max, s.Median = z.sketch.Min(), z.sketch.Max(), z.sketch.Quantile(0.5)
+		for _, level := range acc.opts.Percentiles {
+			s.Percentiles = append(s.Percentiles, z.sketch.Quantile(level/100))
+		}
+		res.Zones = append(res.Zones, s)
+	}
+	sort.Slice(res.Zones, func(i, j int) bool { return res.Zones[i].Zone < res.Zones[j].Zone })
+	return res
+}
@@ -0,0 +1,129 @@
+package zonal_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/nordicsense/gdal"
+	"github.com/nordicsense/modis"
+	"github.com/nordicsense/modis/dataset"
+	"github.com/nordicsense/modis/vector"
+	"github.com/nordicsense/modis/zonal"
+)
+
+func TestByRaster(t *testing.T) {
+	p := modis.ImageParamsBuilder(4, 3).Build()
+	values := dataset.NewInMemory(p)
+	zones := dataset.NewInMemory(p)
+	nan := math.NaN()
+	_ = values.WriteBlock(0, 0, modis.Box{0, 0, 4, 3}, []float64{
+		1, 2, 3, 4,
+		5, nan, 7, 8,
+		9, 10, 11, 12,
+	})
+	_ = zones.WriteBlock(0, 0, modis.Box{0, 0, 4, 3}, []float64{
+		1, 1, 2, 2,
+		1, 1, 2, 2,
+		0, nan, 3, 3,
+	})
+	_ = values.Write(2, 2, nan)
+	_ = values.Write(3, 2, nan)
+	res, err := zonal.ByRaster(values, zones, &zonal.Options{Percentiles: []float64{25, 100}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Zones) != 3 {
+		t.Fatalf("expected 3 zones, found %d", len(res.Zones))
+	}
+	z1, z2, z3 := res.Zones[0], res.Zones[1], res.Zones[2]
+	if z1.Zone != 1 || z1.Count != 4 || z1.Valid != 3 || math.Abs(z1.Mean-8./3) > 1e-12 || z1.Min != 1 || z1.Max != 5 || z1.Median != 2 {
+		t.Errorf("unexpected zone 1 %+v", z1)
+	}
+	if math.Abs(z1.Std-math.Sqrt(26./9)) > 1e-12 || z1.Percentiles[1] != 5 {
+		t.Errorf("unexpected zone 1 %+v", z1)
+	}
+	if z2.Zone != 2 || z2.Valid != 4 || z2.Mean != 5.5 || z2.Median != 5.5 || z2.Percentiles[0] != 3.5 {
+		t.Errorf("unexpected zone 2 %+v", z2)
+	}
+	if z3.Zone != 3 || z3.Count != 2 || z3.Valid != 0 || !math.IsNaN(z3.Mean) || !math.IsNaN(z3.Median) {
+		t.Errorf("unexpected zone 3 %+v", z3)
+	}
+
+	var buf bytes.Buffer
+	if err = res.WriteCSV(&buf); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 4 || lines[0] != "zone,count,valid,weight,mean,std,min,max,median,p25,p100" || !strings.HasPrefix(lines[3], "3,2,0,0,,,,,,,") {
+		t.Errorf("unexpected CSV %q", buf.String())
+	}
+	buf.Reset()
+	if err = res.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if zs := doc["zones"].([]interface{}); len(zs) != 3 || zs[2].(map[string]interface{})["mean"] != nil {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+
+	if _, err = zonal.ByRaster(values, dataset.NewInMemory(modis.ImageParamsBuilder(4, 4).Build()), nil); err == nil {
+		t.Error("expected alignment error")
+	}
+	if _, err = zonal.ByRaster(values, zones, &zonal.Options{Percentiles: []float64{101}}); err == nil {
+		t.Error("expected error for percentile")
+	}
+}
+
+func TestByFeatures(t *testing.T) {
+	sr := gdal.CreateSpatialReference("")
+	defer sr.Destroy()
+	_ = sr.FromEPSG(4326)
+	wkt, _ := sr.ToWKT()
+	// 1 degree pixels from 60N to 90N, values are the row index
+	p := modis.ImageParamsBuilder(4, 30).Transform(modis.AffineTransform{0, 1, 0, 90, 0, -1}).Projection(wkt).Build()
+	values := dataset.NewInMemory(p)
+	for y := 0; y < 30; y++ {
+		for x := 0; x < 4; x++ {
+			_ = values.Write(x, y, float64(y))
+		}
+	}
+	features, err := vector.ReadGeoJSON(strings.NewReader(`{"type": "Polygon", "coordinates": [[[0, 60], [2, 60], [2, 90], [0, 90]]]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := zonal.ByFeatures(values, features, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Zones) != 1 || res.Zones[0].Zone != 1 || res.Zones[0].Valid != 60 || res.Zones[0].Mean != 14.5 {
+		t.Fatalf("unexpected result %+v", res.Zones)
+	}
+	weighted, err := zonal.ByFeatures(values, features, nil, &zonal.Options{AreaWeighted: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// rows towards the south cover more area
+	if z := weighted.Zones[0]; z.Mean <= 14.5 || z.Weight >= 60 {
+		t.Errorf("unexpected weighted result %+v", z)
+	}
+
+	// the second feature lies within the first, both count the shared pixels
+	nested, err := vector.ReadGeoJSON(strings.NewReader(`{"type": "FeatureCollection", "features": [
+		{"type": "Feature", "id": 1, "geometry": {"type": "Polygon", "coordinates": [[[0, 60], [4, 60], [4, 90], [0, 90]]]}},
+		{"type": "Feature", "id": 2, "geometry": {"type": "Polygon", "coordinates": [[[0, 80], [2, 80], [2, 90], [0, 90]]]}}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res, err = zonal.ByFeatures(values, nested, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Zones) != 2 || res.Zones[0].Valid != 120 || math.Abs(res.Zones[0].Mean-14.5) > 1e-9 || res.Zones[1].Valid != 20 || math.Abs(res.Zones[1].Mean-4.5) > 1e-9 {
+		t.Errorf("unexpected result for nested features %+v", res.Zones)
+	}
+}